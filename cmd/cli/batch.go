@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/amettod/hourly-meter/internal/app"
+)
+
+// runBatch converts every export of the directories and patterns with the registry settings
+func runBatch(args []string) {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	registryFile := fs.String("registry", "", "*.json registry of the meters: contract, company name, coefficients and recipient by serial number")
+	output := fs.String("o", ".", "directory of the converted files, each file goes to recipient/accountpoint/80020-MM-YYYY")
+	workers := fs.Int("workers", runtime.NumCPU(), "files converted at the same time")
+	reportFile := fs.String("report", "", "write the results to the *.csv file as well")
+
+	fs.Usage = commandUsage(fs, "batch", " dir|pattern...", fmt.Sprintf("converts the *.html exports of the directories and the files matching the patterns,\nthe meters are found by their serial numbers in the registry,\nexits with %d if any file failed", exitError))
+	fs.Parse(args)
+
+	if *registryFile == "" || fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	registry, err := app.LoadRegistry(*registryFile)
+	if err != nil {
+		fatal(err)
+	}
+
+	filenames, err := expandPatterns(fs.Args())
+	if err != nil {
+		fatal(err)
+	}
+
+	results := registry.Batch(filenames, *output, *workers)
+
+	if *reportFile != "" {
+		err = writeBatchReport(results, *reportFile)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "file\tstatus\tmeters\ttotal, kWh\tdir\n")
+
+	var failed int
+	for _, b := range results {
+		if b.Err != nil {
+			failed++
+			fmt.Fprintf(tw, "%s\tfailed\t%v\t\t%s\n", b.Filename, b.Meters, b.Err)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\tok\t%v\t%.2f\t%s\n", b.Filename, b.Meters, b.Total, b.Dir)
+	}
+	tw.Flush()
+
+	fmt.Printf("%d converted, %d failed\n", len(results)-failed, failed)
+
+	if failed != 0 {
+		os.Exit(exitError)
+	}
+}
+
+// expandPatterns return the *.html files of the directories and the files matching the patterns
+func expandPatterns(patterns []string) ([]string, error) {
+	var filenames []string
+	seen := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+			pattern = filepath.Join(pattern, "*.html")
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no files found", pattern)
+		}
+
+		sort.Strings(matches)
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				filenames = append(filenames, m)
+			}
+		}
+	}
+
+	return filenames, nil
+}
+
+// writeBatchReport writes the results of the batch to the *.csv file
+func writeBatchReport(results []*app.BatchResult, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return app.WriteBatchCSV(f, results)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amettod/hourly-meter/internal/app"
+)
+
+// runConvert converts the meter exports to 80020 files and prints the details of each meter
+func runConvert(args []string) {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	in := addInput(fs)
+	limits := addLimits(fs)
+
+	powerFactor := app.DefaultPowerFactor()
+	fs.IntVar(&powerFactor.PeakStart, "peak-start", powerFactor.PeakStart, "first hour of the tan φ peak window")
+	fs.IntVar(&powerFactor.PeakEnd, "peak-end", powerFactor.PeakEnd, "hour when the tan φ peak window ends")
+	fs.Float64Var(&powerFactor.Limit, "tan-limit", powerFactor.Limit, "the highest allowed tan φ during the peak window")
+	tariffFile := fs.String("tariff", "", "*.json tariff file to estimate the cost of the price categories")
+	export := fs.String("export", "", "spreadsheets of the scaled hourly profile written next to the *.xml files: csv, xlsx or csv,xlsx")
+	layout := fs.String("layout", app.LayoutLong, "layout of the spreadsheets: long (a row per hour) or matrix (a row per day)")
+	tanCSV := fs.String("tan-csv", "", "write hourly tan φ to the *.csv file, the meter serial number is added to the name of several meters")
+	archive := fs.String("archive", "", "write the month files as a zip or tar archive to -o instead of 80020-MM-YYYY, the details go to stderr")
+	output := fs.String("o", "-", "file of the -archive, - is stdout")
+
+	fs.Usage = commandUsage(fs, "convert", "", "writes the 80020 *.xml files of the month and the summary into 80020-MM-YYYY")
+	fs.Parse(args)
+
+	if *archive != "" && *archive != app.ArchiveZip && *archive != app.ArchiveTar {
+		fmt.Fprintf(os.Stderr, "bad archive %s\n", *archive)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	fail := fatal
+
+	// started without flags from a console, e.g. by a double click on Windows, or without the contract
+	var p *prompter
+	if in.missing() && isTerminal(os.Stdin) {
+		p = newPrompter()
+		fail = p.fatal
+		defer p.wait()
+
+		err := p.input(in)
+		if err != nil {
+			fail(err)
+		}
+	}
+
+	apps, err := in.load()
+	if err != nil {
+		fail(err)
+	}
+
+	if p != nil {
+		ok, err := p.preview(apps)
+		if err != nil {
+			fail(err)
+		}
+		if !ok {
+			return
+		}
+		p.remember(apps)
+	}
+
+	for _, a := range apps {
+		for _, c := range a.Conflicts {
+			log.Printf("conflict: %s", c)
+		}
+	}
+
+	exports, err := app.NewExport(strings.Split(*export, ","), *layout)
+	if err != nil {
+		fail(usageError{err})
+	}
+	for _, a := range apps {
+		a.Export = exports
+	}
+
+	var tariff *app.Tariff
+	if *tariffFile != "" {
+		tariff, err = app.LoadTariff(*tariffFile)
+		if err != nil {
+			fail(err)
+		}
+	}
+
+	// the details are printed to stderr when stdout is the archive
+	out := io.Writer(os.Stdout)
+	if *archive != "" {
+		if *output == "-" {
+			out = os.Stderr
+		}
+		err = writeArchive(apps, *archive, *output)
+		if err != nil {
+			fail(err)
+		}
+	} else {
+		err = app.RunAll(apps)
+		if err != nil {
+			fail(err)
+		}
+
+		dirName := app.DirName(apps[0].Month, apps[0].Year)
+		fmt.Fprintf(out, "summary:\t%s\n", strings.Join([]string{
+			filepath.Join(dirName, "summary.txt"),
+			filepath.Join(dirName, "summary.html"),
+			filepath.Join(dirName, "summary.json"),
+		}, ", "))
+	}
+
+	for _, a := range apps {
+		fmt.Fprintf(out, "meter:\t%s\ntotal:\t%.2f kWh\nvalues:\t%d\n", a.Meter, a.Total, len(a.Rows))
+		if a.Coefficients != nil {
+			for _, u := range a.CoefficientUsage() {
+				fmt.Fprintf(out, "coefficient:\t%s\n", u)
+			}
+		}
+		if a.CapacityErr != "" {
+			fmt.Fprintf(out, "capacity:\t%s\n", a.CapacityErr)
+		} else if a.PeakHours != nil {
+			fmt.Fprintf(out, "capacity:\t%.3f MW\n", a.Capacity)
+		}
+		for _, z := range a.Zones {
+			fmt.Fprintf(out, "%s:\t%.2f kWh\n", z.Name, z.Total)
+		}
+		if a.Reconciliation != nil {
+			fmt.Fprintf(out, "reconciliation:\t%s\n", a.Reconciliation)
+		}
+
+		anomalies := a.Analyze(*limits)
+		if len(anomalies) != 0 {
+			fmt.Fprintf(out, "anomalies:\n")
+		}
+		for _, anomaly := range anomalies {
+			fmt.Fprintf(out, "\t%s\n", anomaly)
+		}
+
+		tanPhi := a.TanPhi(powerFactor)
+		fmt.Fprintf(out, "tan φ:\t%.3f month, %.3f peak, %d hours above %.2f\n", tanPhi.Month, tanPhi.Peak, tanPhi.Violations(), powerFactor.Limit)
+		for _, d := range tanPhi.Days {
+			fmt.Fprintf(out, "\t%s: %d hours, max %.3f\n", d.Date.Format("02.01.2006"), d.Hours, d.Max)
+		}
+
+		if tariff != nil {
+			printCosts(out, a.Costs(tariff))
+		}
+
+		if *tanCSV != "" {
+			err = writeTanPhi(tanPhi, csvName(*tanCSV, a.Meter, len(apps)))
+			if err != nil {
+				fail(err)
+			}
+		}
+	}
+}
+
+// writeArchive writes the month files of the apps as an archive to the file, - is stdout
+func writeArchive(apps []*app.App, format, filename string) error {
+	tmp, err := os.MkdirTemp("", "80020-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	dirName, err := app.RunAllIn(tmp, apps)
+	if err != nil {
+		return err
+	}
+
+	if filename == "-" {
+		return app.WriteArchive(os.Stdout, dirName, format)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return app.WriteArchive(f, dirName, format)
+}
+
+// writeTanPhi writes hourly tan φ to the file
+func writeTanPhi(r *app.TanPhiReport, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return r.WriteCSV(f)
+}
+
+// csvName adds the meter serial number to the filename if there are several meters
+func csvName(filename, meter string, meters int) string {
+	if meters == 1 {
+		return filename
+	}
+
+	ext := filepath.Ext(filename)
+
+	return strings.TrimSuffix(filename, ext) + "_" + meter + ext
+}
+
+// printCosts prints the cost breakdown of the price categories
+func printCosts(w io.Writer, costs []*app.Cost) {
+	fmt.Fprintf(w, "cost:\tcategory\tenergy\tcapacity\ttransmission\ttotal, rub\n")
+	for _, c := range costs {
+		if c.Err != nil {
+			fmt.Fprintf(w, "\t%d\t%s\n", c.Category, c.Err)
+			continue
+		}
+		fmt.Fprintf(w, "\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%s\n", c.Category, c.Energy, c.Capacity, c.Transmission, c.Total, c.Note)
+	}
+
+	if cheapest := app.Cheapest(costs); cheapest != nil {
+		fmt.Fprintf(w, "cheapest:\tcategory %d, %.2f rub\n", cheapest.Category, cheapest.Total)
+	}
+}
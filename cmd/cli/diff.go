@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/amettod/hourly-meter/internal/app"
+)
+
+// runDiff compares the hourly values of two datasets
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	contract := fs.String("contract", "", "contract number cut off the 80020 accountpoint codes")
+	coefficient := fs.Float64("coefficient", 1, "power factory of the configurator exports, the 80020 values are already scaled")
+	threshold := fs.Float64("threshold", 0.001, "the hours differing by no more than threshold kWh are not reported")
+	format := fs.String("format", "table", "output format: table or csv")
+	output := fs.String("o", "", "write the output to the file instead of stdout")
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s diff [flags] left right\nleft and right are *.html exports, 80020 *.xml files, directories or *.zip archives\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if *format != "table" && *format != "csv" {
+		fmt.Fprintf(os.Stderr, "bad format %s\n", *format)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	def := app.Mapping{Contract: *contract, Coefficient: *coefficient}
+
+	left, err := app.Open(fs.Arg(0), "", def)
+	if err != nil {
+		fatal(err)
+	}
+
+	right, err := app.Open(fs.Arg(1), "", def)
+	if err != nil {
+		fatal(err)
+	}
+
+	diffs := app.Compare(left, right, *threshold)
+
+	w := os.Stdout
+	if *output != "" {
+		w, err = os.Create(*output)
+		if err != nil {
+			fatal(err)
+		}
+		defer w.Close()
+	}
+
+	if *format == "csv" {
+		err = app.WriteDiffCSV(w, diffs)
+	} else {
+		err = printDiffs(w, diffs)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// printDiffs prints the differences as a table followed by the totals of each meter
+func printDiffs(w io.Writer, diffs []*app.MeterDiff) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintf(tw, "meter\tdate\thour\tleft\tright\tdelta\tmissing\t\n")
+	for _, d := range diffs {
+		for _, h := range d.Hours {
+			fmt.Fprintf(tw, "%s\t%s\t%02d\t%.4f\t%.4f\t%.4f\t%s\t\n", d.Meter, h.Start.Format("02.01.2006"), h.Start.Hour(), h.Left, h.Right, h.Delta, h.Missing)
+		}
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range diffs {
+		fmt.Fprintf(w, "meter %s: left %.2f kWh, right %.2f kWh, delta %.2f kWh, %d hours differ, %d missing on the left, %d missing on the right\n",
+			d.Meter, d.LeftTotal, d.RightTotal, d.Delta, len(d.Hours)-d.MissingLeft-d.MissingRight, d.MissingLeft, d.MissingRight)
+	}
+
+	return nil
+}
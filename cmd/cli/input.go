@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amettod/hourly-meter/internal/app"
+)
+
+// input the flags selecting the meter data and its settings, shared by the commands
+type input struct {
+	filename      string
+	contract      string
+	companyName   string
+	meter         string
+	coefficient   float64
+	mapping       string
+	registry      string
+	zones         string
+	peakHours     string
+	calendar      string
+	splice        string
+	coefficients  string
+	group         string
+	registerStart string
+	registerEnd   string
+	registers     string
+	tolerance     float64
+	correct       bool
+}
+
+// addInput registers the input flags on the flag set
+func addInput(fs *flag.FlagSet) *input {
+	in := &input{}
+
+	fs.StringVar(&in.filename, "filename", "", "filename *.html, several exports of one meter are separated by commas, or 80020 *.xml files and directories, - reads an export from stdin")
+	fs.StringVar(&in.contract, "contract", "", "contract number")
+	fs.StringVar(&in.companyName, "name", "", "company name")
+	fs.StringVar(&in.meter, "meter", "", "electronic meter serial number, only this meter is converted")
+	fs.Float64Var(&in.coefficient, "coefficient", 1, "power factory")
+	fs.StringVar(&in.mapping, "map", "", "contract and power factory of each meter: serial=contract:coefficient,...")
+	fs.StringVar(&in.registry, "registry", "", "*.json registry of the meters, it replaces -contract, -name, -coefficient and -map")
+	fs.StringVar(&in.zones, "zones", "", "tariff zones: day-night, three-zone or a *.json schedule file")
+	fs.StringVar(&in.peakHours, "peak-hours", "", "*.csv file of the system operator's planned peak hours to calculate the purchased capacity")
+	fs.StringVar(&in.calendar, "calendar", "", "production calendar *.xml files separated by commas, they replace the bundled years")
+	fs.StringVar(&in.splice, "splice", "", "*.csv file of the meter segments of one accountpoint: filename;meter;coefficient;from;to, -meter is the accountpoint code")
+	fs.StringVar(&in.coefficients, "coefficients", "", "*.csv file of the coefficient history: meter;from;to;ct;vt")
+	fs.StringVar(&in.group, "group", "", "meters combined hour by hour into their own accountpoint: name=contract:+serial-serial;...")
+	fs.StringVar(&in.registerStart, "register-start", "", "active energy register reading at the start of the month")
+	fs.StringVar(&in.registerEnd, "register-end", "", "active energy register reading at the end of the month")
+	fs.StringVar(&in.registers, "registers", "", "*.csv file of the register readings of several meters: meter;start;end")
+	fs.Float64Var(&in.tolerance, "tolerance", 0.5, "allowed discrepancy between the profile and the register readings in percent")
+	fs.BoolVar(&in.correct, "correct", false, "scale the hourly values proportionally to the register readings")
+
+	return in
+}
+
+// addLimits registers the flags of the anomaly analysis on the flag set
+func addLimits(fs *flag.FlagSet) *app.Limits {
+	limits := app.DefaultLimits()
+
+	fs.Float64Var(&limits.Sigma, "sigma", limits.Sigma, "spike threshold in standard deviations of the same weekday and hour, 0 disables")
+	fs.IntVar(&limits.ZeroHours, "zero-hours", limits.ZeroHours, "consecutive working hours without consumption, 0 disables")
+	fs.IntVar(&limits.FrozenHours, "frozen-hours", limits.FrozenHours, "consecutive hours with the same value, 1 and 2 report any value repeated in the next hour, 0 disables")
+	fs.Float64Var(&limits.MinRatio, "min-ratio", limits.MinRatio, "lower limit of Q+/P+, 0 disables")
+	fs.Float64Var(&limits.MaxRatio, "max-ratio", limits.MaxRatio, "upper limit of Q+/P+, 0 disables")
+	fs.BoolVar(&limits.ConsumptionOnly, "consumption-only", limits.ConsumptionOnly, "report any P- of the site")
+
+	return &limits
+}
+
+// load return the apps of the input with the settings applied,
+// the register readings are reconciled and the groups are added after the meters
+func (in *input) load() ([]*app.App, error) {
+	if in.filename == "" && in.splice == "" {
+		return nil, usageError{errors.New("-filename or -splice is required")}
+	}
+
+	mappings, err := app.ParseMappings(in.mapping)
+	if err != nil {
+		return nil, usageError{fmt.Errorf("-map: %w", err)}
+	}
+
+	var apps []*app.App
+
+	filenames, err := expandDirs(strings.Split(in.filename, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	def := app.Mapping{Contract: in.contract, Coefficient: in.coefficient}
+
+	if in.splice != "" {
+		if in.meter == "" {
+			return nil, usageError{errors.New("-meter is required as the accountpoint code of the segments")}
+		}
+		segments, err := app.LoadSegments(in.splice)
+		if err != nil {
+			return nil, err
+		}
+		a, err := app.NewSpliced(segments, in.contract, in.companyName, in.meter)
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, a)
+	} else if in.filename == "-" {
+		apps, err = app.NewAllFrom(os.Stdin, "stdin", in.companyName, def, mappings)
+		if err != nil {
+			return nil, err
+		}
+		if in.meter != "" {
+			a, err := selectApp(apps, in.meter)
+			if err != nil {
+				return nil, err
+			}
+			apps = []*app.App{a}
+		}
+	} else if app.IsXML(filenames) {
+		apps, err = app.NewXML(filenames, in.companyName, def, mappings)
+		if err != nil {
+			return nil, err
+		}
+	} else if len(filenames) > 1 {
+		a, err := app.NewMerged(filenames, in.contract, in.companyName, in.meter, in.coefficient)
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, a)
+	} else if in.meter != "" {
+		a, err := app.New(in.filename, in.contract, in.companyName, in.meter, in.coefficient)
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, a)
+	} else {
+		apps, err = app.NewAll(in.filename, in.companyName, def, mappings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if in.registry != "" {
+		registry, err := app.LoadRegistry(in.registry)
+		if err != nil {
+			return nil, err
+		}
+		err = registry.Apply(apps)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	schedule, err := app.LoadSchedule(in.zones)
+	if err != nil {
+		return nil, err
+	}
+
+	var calendar *app.Calendar
+	if in.calendar != "" {
+		calendar, err = app.LoadCalendar(strings.Split(in.calendar, ",")...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var peakHours app.PeakHours
+	if in.peakHours != "" {
+		peakHours, err = app.LoadPeakHours(in.peakHours)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var coefficients map[string][]*app.CoefficientPeriod
+	if in.coefficients != "" {
+		coefficients, err = app.LoadCoefficients(in.coefficients)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, a := range apps {
+		a.Schedule = schedule
+		a.Calendar = calendar
+		a.PeakHours = peakHours
+		if c, ok := coefficients[a.Meter]; ok {
+			a.Coefficients = c
+		}
+	}
+
+	registers := make(app.Registers)
+	if in.registers != "" {
+		registers, err = app.LoadRegisters(in.registers)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if in.registerStart != "" || in.registerEnd != "" {
+		if len(apps) != 1 {
+			return nil, usageError{errors.New("-register-start and -register-end are for a single meter, use -registers")}
+		}
+		registers[apps[0].Meter], err = app.ParseRegister(in.registerStart, in.registerEnd)
+		if err != nil {
+			return nil, usageError{err}
+		}
+	}
+
+	for _, a := range apps {
+		r, ok := registers[a.Meter]
+		if !ok {
+			continue
+		}
+
+		rec, err := a.Reconcile(r, in.tolerance)
+		if err != nil {
+			return nil, err
+		}
+		if in.correct && !rec.OK() {
+			err = a.Correct(rec)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	groups, err := app.ParseGroups(in.group)
+	if err != nil {
+		return nil, usageError{fmt.Errorf("-group: %w", err)}
+	}
+	for _, g := range groups {
+		a, err := app.NewGroup(g, apps)
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, a)
+	}
+
+	return apps, nil
+}
+
+// selectApp return App of the meter, otherwise the only one with the meter serial number set
+func selectApp(apps []*app.App, meter string) (*app.App, error) {
+	for _, a := range apps {
+		if a.Meter == meter {
+			return a, nil
+		}
+	}
+
+	if len(apps) > 1 {
+		return nil, fmt.Errorf("meter %s not found", meter)
+	}
+
+	apps[0].Meter = meter
+
+	return apps[0], nil
+}
+
+// expandDirs replaces the directories with their *.xml files
+func expandDirs(filenames []string) ([]string, error) {
+	var expanded []string
+
+	for _, filename := range filenames {
+		info, err := os.Stat(filename)
+		if err != nil || !info.IsDir() {
+			expanded = append(expanded, filename)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(filename, "*.xml"))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no *.xml files found", filename)
+		}
+		expanded = append(expanded, matches...)
+	}
+
+	return expanded, nil
+}
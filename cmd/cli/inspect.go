@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/amettod/hourly-meter/internal/app"
+)
+
+// runInspect prints the meters, their period, row counts, the day × hour table of P+ and its charts
+func runInspect(args []string) {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	in := addInput(fs)
+	precision := fs.Int("precision", 2, "decimal places of the hourly values")
+	view := fs.String("view", "table,heatmap,bars", "parts printed after the meter details separated by commas: table, heatmap and bars")
+	ascii := fs.Bool("ascii", runtime.GOOS == "windows", "draw the charts by ASCII characters")
+	width := fs.Int("width", 50, "length of the longest bar of the daily totals")
+	shift := fs.Int("shift", 0, "hours added to the meter time in the charts, the shift of -zones by default")
+
+	fs.Usage = commandUsage(fs, "inspect", "", "prints the meters and the scaled hourly P+ in kWh, a missing hour is shown as -,\nthe heatmap shades each hour by its share of the highest hour of the month marked as ◆ (@ in ASCII)")
+	fs.Parse(args)
+
+	views := make(map[string]bool)
+	for _, v := range strings.Split(*view, ",") {
+		switch v = strings.TrimSpace(v); v {
+		case "table", "heatmap", "bars":
+			views[v] = true
+		case "":
+		default:
+			fmt.Fprintf(os.Stderr, "bad view %s\n", v)
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+	}
+
+	if *width < 1 {
+		fmt.Fprintf(os.Stderr, "bad width %d\n", *width)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	shifted := false
+	fs.Visit(func(f *flag.Flag) { shifted = shifted || f.Name == "shift" })
+
+	apps, err := in.load()
+	if err != nil {
+		fatal(err)
+	}
+
+	report, err := app.NewReport(apps)
+	if err != nil {
+		fatal(err)
+	}
+
+	for i, a := range apps {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		err = printInspect(os.Stdout, a, report.Meters[i], *precision, views["table"])
+		if err != nil {
+			fatal(err)
+		}
+
+		hours := *shift
+		if !shifted && a.Schedule != nil {
+			hours = a.Schedule.Shift
+		}
+		chart := a.Chart(hours)
+
+		if views["heatmap"] {
+			fmt.Println()
+			err = chart.WriteHeatmap(os.Stdout, *ascii)
+			if err != nil {
+				fatal(err)
+			}
+		}
+		if views["bars"] {
+			fmt.Println()
+			err = chart.WriteBars(os.Stdout, *width, *ascii)
+			if err != nil {
+				fatal(err)
+			}
+		}
+	}
+}
+
+// printInspect prints the meter details followed by its day × hour table if table is true
+func printInspect(w io.Writer, a *app.App, m *app.MeterReport, precision int, table bool) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+
+	fmt.Fprintf(tw, "meter:\t%s\ncontract:\t%s\ncompany:\t%s\n", a.Meter, a.Contract, a.CompanyName)
+	for _, u := range a.CoefficientUsage() {
+		fmt.Fprintf(tw, "coefficient:\t%s\n", u)
+	}
+	if len(a.Rows) != 0 {
+		fmt.Fprintf(tw, "period:\t%s - %s\n",
+			a.Rows[0].Date.Add(-time.Hour).Format("02.01.2006 15:04"), a.Rows[len(a.Rows)-1].Date.Format("02.01.2006 15:04"))
+	}
+	fmt.Fprintf(tw, "rows:\t%d\ngaps:\t%d hours\nestimated:\t%d hours\ntotal:\t%.2f kWh\n", m.Values, m.Gaps, m.Estimated, m.Total)
+	err := tw.Flush()
+	if err != nil || !table {
+		return err
+	}
+
+	fmt.Fprintln(w)
+
+	tw = tabwriter.NewWriter(w, 0, 8, 1, ' ', tabwriter.AlignRight)
+	for _, row := range a.Table(app.LayoutMatrix) {
+		for _, cell := range row {
+			switch v := cell.(type) {
+			case nil:
+				fmt.Fprint(tw, "-\t")
+			case float64:
+				fmt.Fprintf(tw, "%.*f\t", precision, v)
+			default:
+				fmt.Fprintf(tw, "%v\t", v)
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+
+	return tw.Flush()
+}
@@ -1,31 +1,130 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"strings"
+)
 
-	"github.com/amettod/hourly-meter/internal/app"
+// Exit codes of the commands
+const (
+	exitOK       = 0 // the command succeeded
+	exitError    = 1 // the input can't be read, converted or written
+	exitUsage    = 2 // bad command line
+	exitProblems = 3 // validate found problems in the input
 )
 
+// usageError a mistake of the command line, the command exits with exitUsage
+type usageError struct {
+	error
+}
+
+// exitCode return exitUsage for a usage error, otherwise exitError
+func exitCode(err error) int {
+	var u usageError
+	if errors.As(err, &u) {
+		return exitUsage
+	}
+
+	return exitError
+}
+
+// fatal prints err and exits with its exit code
+func fatal(err error) {
+	log.Print(err)
+	os.Exit(exitCode(err))
+}
+
+// command a subcommand of the CLI
+type command struct {
+	name  string
+	short string
+	run   func(args []string)
+}
+
+// commands the subcommands in the order of the help text
+var commands = []*command{
+	{name: "convert", short: "convert the meter exports to 80020 *.xml files with the summary", run: runConvert},
+	{name: "validate", short: "check the meter exports without writing any file", run: runValidate},
+	{name: "inspect", short: "print the meters, the period, row counts and a day × hour table", run: runInspect},
+	{name: "report", short: "print the monthly summary as text, HTML or JSON", run: runReport},
+	{name: "batch", short: "convert a directory of exports with the settings of the registry", run: runBatch},
+	{name: "watch", short: "convert the exports saved into an inbox directory until stopped", run: runWatch},
+	{name: "plan", short: "forecast the hourly consumption of the next month", run: runPlan},
+	{name: "diff", short: "compare the hourly values of two datasets", run: runDiff},
+}
+
 func main() {
-	filename := flag.String("filename", "", "filename *.html")
-	contract := flag.String("contract", "", "contract number")
-	companyName := flag.String("name", "", "company name")
-	meter := flag.String("meter", "", "electronic meter serial number")
-	coefficient := flag.Float64("coefficient", 1, "power factory")
+	if len(os.Args) < 2 {
+		if isTerminal(os.Stdin) {
+			runConvert(nil)
+			return
+		}
+		usage()
+		os.Exit(exitUsage)
+	}
 
-	flag.Parse()
+	// flags without a command are the flags of convert as before the commands were added
+	if strings.HasPrefix(os.Args[1], "-") && os.Args[1] != "-h" && os.Args[1] != "-help" && os.Args[1] != "--help" {
+		runConvert(os.Args[1:])
+		return
+	}
+
+	name, args := os.Args[1], os.Args[2:]
 
-	app, err := app.New(*filename, *contract, *companyName, *meter, *coefficient)
-	if err != nil {
-		log.Fatal(err)
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) == 0 {
+			usage()
+			os.Exit(exitOK)
+		}
+		name, args = args[0], []string{"-h"}
 	}
 
-	err = app.Run()
-	if err != nil {
-		log.Fatal()
+	c := findCommand(name)
+	if c == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %s\n\n", name)
+		usage()
+		os.Exit(exitUsage)
 	}
 
-	fmt.Printf("total:\t%.2f kWh\nvalues:\t%d\n", app.Total, len(app.Rows))
+	c.run(args)
+}
+
+// findCommand return the command by its name or nil
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// usage prints the commands and the exit codes
+func usage() {
+	w := os.Stderr
+
+	fmt.Fprintf(w, "usage: %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", c.name, c.short)
+	}
+	fmt.Fprintf(w, "\nrun %s help <command> for the flags of a command,\nflags without a command run convert\n", os.Args[0])
+	fmt.Fprintf(w, "\nexit codes:\n"+
+		"  %d  success\n"+
+		"  %d  the input can't be read, converted or written\n"+
+		"  %d  bad command line\n"+
+		"  %d  validate found problems in the input\n", exitOK, exitError, exitUsage, exitProblems)
+}
+
+// commandUsage return the usage function of the command flag set
+func commandUsage(fs *flag.FlagSet, name, args, text string) func() {
+	return func() {
+		fmt.Fprintf(fs.Output(), "usage: %s %s [flags]%s\n%s\n\nflags:\n", os.Args[0], name, args, text)
+		fs.PrintDefaults()
+	}
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cliArgs the environment variable with the arguments of the CLI run by the test binary
+const cliArgs = "HOURLY_METER_CLI_ARGS"
+
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv(cliArgs); ok {
+		os.Args = append([]string{"cli"}, strings.Fields(args)...)
+		main()
+		os.Exit(exitOK)
+	}
+
+	os.Exit(m.Run())
+}
+
+// runCLI runs the CLI with the args in the directory and return its exit code and output
+func runCLI(t *testing.T, dir string, args ...string) (int, string) {
+	t.Helper()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(exe)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), cliArgs+"="+strings.Join(args, " "))
+	cmd.Stdin = strings.NewReader("")
+
+	out, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return exit.ExitCode(), string(out)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return exitOK, string(out)
+}
+
+// testdata return the absolute path of the file of the library testdata
+func testdata(t *testing.T, name string) string {
+	t.Helper()
+
+	filename, err := filepath.Abs(filepath.Join("..", "..", "internal", "app", "testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+func Test_exitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "usage", err: usageError{errors.New("-filename or -splice is required")}, want: exitUsage},
+		{name: "wrapped usage", err: fmt.Errorf("input: %w", usageError{errors.New("bad")}), want: exitUsage},
+		{name: "other", err: errors.New("file not found"), want: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMain_commands(t *testing.T) {
+	feb := testdata(t, "23456789_feb.html")
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    int
+		wantOut string
+	}{
+		{name: "flags without a command convert", args: []string{"-filename", feb, "-coefficient", "4000"}, want: exitOK, wantOut: "summary:"},
+		{name: "convert", args: []string{"convert", "-filename", feb}, want: exitOK, wantOut: "summary:"},
+		{name: "unknown command", args: []string{"bad"}, want: exitUsage, wantOut: "unknown command bad"},
+		{name: "missing filename", args: []string{"convert", "-meter", "1"}, want: exitUsage, wantOut: "-filename or -splice is required"},
+		{name: "bad diff format", args: []string{"diff", "-format", "bad", feb, feb}, want: exitUsage, wantOut: "bad format bad"},
+		{name: "missing file", args: []string{"report", "-filename", "missing.html"}, want: exitError},
+		{name: "clean month", args: []string{"validate", "-filename", feb, "-coefficient", "4000"}, want: exitOK, wantOut: "ok: 1 meters"},
+		{name: "month with gaps", args: []string{"validate", "-filename", testdata(t, "first_row.html")}, want: exitProblems},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			got, out := runCLI(t, dir, tt.args...)
+			if got != tt.want || !strings.Contains(out, tt.wantOut) {
+				t.Errorf("cli %s = %d, want %d with %q:\n%s", strings.Join(tt.args, " "), got, tt.want, tt.wantOut, out)
+			}
+		})
+	}
+
+	// the flags without a command write the files of convert
+	dir := t.TempDir()
+	if got, out := runCLI(t, dir, "-filename", feb); got != exitOK {
+		t.Fatalf("cli -filename = %d:\n%s", got, out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "80020-02-2020", "summary.txt")); err != nil {
+		t.Errorf("cli -filename didn't convert: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/amettod/hourly-meter/internal/app"
+)
+
+// runPlan makes the hourly consumption plan of the next day or month from the history exports
+func runPlan(args []string) {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	history := fs.String("history", "", "past months *.html of one meter separated by commas")
+	contract := fs.String("contract", "", "contract number")
+	companyName := fs.String("name", "", "company name")
+	meter := fs.String("meter", "", "electronic meter serial number")
+	coefficient := fs.Float64("coefficient", 1, "power factory")
+	day := fs.String("day", "", "plan the day YYYY-MM-DD")
+	month := fs.String("month", "", "plan the month YYYY-MM, the month following the history by default")
+	format := fs.String("format", "xml", "plan format: xml or csv")
+	dir := fs.String("dir", "", "directory of the plan, plan-80020-MM-YYYY by default")
+	calendarFiles := fs.String("calendar", "", "production calendar *.xml files separated by commas, they replace the bundled years")
+	backtest := fs.String("backtest", "", "actual *.html of the month to compare with the plan of the history")
+
+	fs.Parse(args)
+
+	if *history == "" {
+		fmt.Fprintln(os.Stderr, "-history is required")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if *format != "xml" && *format != "csv" {
+		fmt.Fprintf(os.Stderr, "bad format %s\n", *format)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	var apps []*app.App
+	for _, filename := range strings.Split(*history, ",") {
+		a, err := app.New(filename, *contract, *companyName, *meter, *coefficient)
+		if err != nil {
+			fatal(err)
+		}
+		apps = append(apps, a)
+	}
+
+	var calendar *app.Calendar
+	if *calendarFiles != "" {
+		var err error
+		calendar, err = app.LoadCalendar(strings.Split(*calendarFiles, ",")...)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	if *backtest != "" {
+		actual, err := app.New(*backtest, *contract, *companyName, *meter, *coefficient)
+		if err != nil {
+			fatal(err)
+		}
+
+		b, err := app.NewBacktest(apps, actual, calendar)
+		if err != nil {
+			fatal(err)
+		}
+
+		fmt.Printf("hours:\t%d\nactual:\t%.2f kWh\nplanned:\t%.2f kWh\nMAE:\t%.2f kWh\nWAPE:\t%.2f %%\n", b.Hours, b.Actual, b.Planned, b.MAE, b.WAPE)
+		return
+	}
+
+	f, err := app.NewForecast(apps, calendar)
+	if err != nil {
+		fatal(err)
+	}
+
+	var plan *app.Plan
+
+	switch {
+	case *day != "":
+		t, err := time.Parse("2006-01-02", *day)
+		if err != nil {
+			fatal(usageError{fmt.Errorf("-day: %w", err)})
+		}
+		plan = f.DayPlan(t, *contract, *companyName, apps[0].Meter)
+	case *month != "":
+		t, err := time.Parse("2006-01", *month)
+		if err != nil {
+			fatal(usageError{fmt.Errorf("-month: %w", err)})
+		}
+		plan = f.MonthPlan(t.Year(), t.Month(), *contract, *companyName, apps[0].Meter)
+	default:
+		next := f.Next()
+		if next.Day() != 1 { // the history ends inside the month
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		}
+		plan = f.MonthPlan(next.Year(), next.Month(), *contract, *companyName, apps[0].Meter)
+	}
+
+	if *dir == "" {
+		*dir = "plan-80020-" + plan.Hours[0].Start.Format("01-2006")
+	}
+
+	err = os.MkdirAll(*dir, 0755)
+	if err != nil {
+		fatal(err)
+	}
+
+	if *format == "csv" {
+		err = writePlan(plan, *dir+"/plan.csv")
+	} else {
+		err = plan.WriteXML(*dir)
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	var total float64
+	for _, h := range plan.Hours {
+		total += h.Value
+	}
+	fmt.Printf("meter:\t%s\ntrend:\t%.3f\nhours:\t%d\nplanned:\t%.2f kWh\ndir:\t%s\n", plan.Meter, f.Trend, len(plan.Hours), total, *dir)
+}
+
+// writePlan writes planned hours to the file
+func writePlan(p *app.Plan, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return p.WriteCSV(f)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/amettod/hourly-meter/internal/app"
+)
+
+// prompter asks for the missing input when the CLI is started without flags from a console
+type prompter struct {
+	r *bufio.Reader
+	w io.Writer
+
+	memory     app.Registry // the settings remembered from the earlier runs
+	memoryFile string
+}
+
+// isTerminal return true if the file is a console rather than a pipe or a file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// newPrompter return prompter of the console with the remembered settings
+func newPrompter() *prompter {
+	p := &prompter{r: bufio.NewReader(os.Stdin), w: os.Stdout, memory: make(app.Registry)}
+
+	if dir, err := os.UserConfigDir(); err == nil {
+		p.memoryFile = filepath.Join(dir, "hourly-meter", "meters.json")
+		if memory, err := app.LoadRegistry(p.memoryFile); err == nil {
+			p.memory = memory
+		}
+	}
+
+	return p
+}
+
+// ask prints the question with the default answer and return the answer, the default if it is empty
+func (p *prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.w, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.w, "%s: ", question)
+	}
+
+	line, err := p.r.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+
+	// a file dragged into the console window is quoted
+	answer := strings.Trim(strings.TrimSpace(line), `"'`)
+	if answer == "" {
+		return def, nil
+	}
+
+	return answer, nil
+}
+
+// askRequired asks until the answer is not empty
+func (p *prompter) askRequired(question, def string) (string, error) {
+	for {
+		answer, err := p.ask(question, def)
+		if err != nil || answer != "" {
+			return answer, err
+		}
+	}
+}
+
+// askFloat asks until the answer is a positive number, a decimal comma is allowed
+func (p *prompter) askFloat(question string, def float64) (float64, error) {
+	for {
+		answer, err := p.ask(question, strconv.FormatFloat(def, 'f', -1, 64))
+		if err != nil {
+			return 0, err
+		}
+
+		v, err := strconv.ParseFloat(strings.Replace(answer, ",", ".", 1), 64)
+		if err == nil && v > 0 {
+			return v, nil
+		}
+		fmt.Fprintf(p.w, "a positive number required\n")
+	}
+}
+
+// confirm return true if the answer is yes
+func (p *prompter) confirm(question string) bool {
+	answer, err := p.ask(question+" (y/n)", "y")
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(answer) {
+	case "y", "yes", "д", "да":
+		return true
+	}
+
+	return false
+}
+
+// missing return true if the export or the contract of a single export is not given,
+// the contract is not required when it comes from -map, -registry or the 80020 files
+func (in *input) missing() bool {
+	if in.filename == "" {
+		return in.splice == ""
+	}
+	if in.contract != "" || in.mapping != "" || in.registry != "" || in.filename == "-" || app.IsXML([]string{in.filename}) {
+		return false
+	}
+
+	info, err := os.Stat(in.filename)
+
+	return err == nil && !info.IsDir()
+}
+
+// input asks for the missing export and the settings of its meters, the detected meters, the given flags
+// and the remembered settings are offered as the default answers
+func (p *prompter) input(in *input) error {
+	var err error
+	if in.filename == "" {
+		fmt.Fprintf(p.w, "No flags given, answer the questions or press Ctrl+C, run %s help for the commands.\n", filepath.Base(os.Args[0]))
+
+		in.filename, err = p.askRequired("export file *.html", "")
+		if err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(p.w, "No -contract given, answer the questions or press Ctrl+C.\n")
+	}
+
+	apps, err := app.NewAll(in.filename, "", app.Mapping{Coefficient: 1}, nil)
+	if err != nil {
+		return err
+	}
+
+	var meters []string
+	for _, a := range apps {
+		meters = append(meters, a.Meter)
+	}
+	fmt.Fprintf(p.w, "meters found: %s\n", strings.Join(meters, ", "))
+
+	if in.meter != "" {
+		meters = []string{in.meter}
+	} else if len(meters) == 1 {
+		in.meter = meters[0]
+	} else {
+		answer, err := p.ask("meter to convert, empty for all", "")
+		if err != nil {
+			return err
+		}
+		in.meter = answer
+		if answer != "" {
+			meters = []string{answer}
+		}
+	}
+
+	remembered := func(meter string) *app.Client {
+		c := &app.Client{CompanyName: in.companyName, Coefficient: in.coefficient}
+		if m, ok := p.memory[meter]; ok {
+			c.Contract = m.Contract
+			if c.CompanyName == "" {
+				c.CompanyName = m.CompanyName
+			}
+			if c.Coefficient == 1 {
+				c.Coefficient = m.Coefficient
+			}
+		}
+		return c
+	}
+
+	in.companyName, err = p.askRequired("company name", remembered(meters[0]).CompanyName)
+	if err != nil {
+		return err
+	}
+
+	var mappings []string
+	for _, meter := range meters {
+		c := remembered(meter)
+
+		contract, err := p.askRequired(fmt.Sprintf("meter %s: contract", meter), c.Contract)
+		if err != nil {
+			return err
+		}
+
+		coefficient, err := p.askFloat(fmt.Sprintf("meter %s: power factory", meter), c.Coefficient)
+		if err != nil {
+			return err
+		}
+
+		in.contract, in.coefficient = contract, coefficient
+		mappings = append(mappings, fmt.Sprintf("%s=%s:%v", meter, contract, coefficient))
+	}
+	if len(mappings) > 1 {
+		in.mapping = strings.Join(mappings, ",")
+	}
+
+	return nil
+}
+
+// preview prints the totals of the meters and return true if the files should be written
+func (p *prompter) preview(apps []*app.App) (bool, error) {
+	report, err := app.NewReport(apps)
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Fprintf(p.w, "\n%02d.%d\n", report.Month, report.Year)
+	for _, m := range report.Meters {
+		fmt.Fprintf(p.w, "meter %s, contract %s: %.2f kWh, %d values, %d hours without values, %d estimated\n",
+			m.Meter, m.Contract, m.Total, m.Values, m.Gaps, m.Estimated)
+	}
+
+	return p.confirm("write the files"), nil
+}
+
+// remember saves the settings of the meters for the next runs
+func (p *prompter) remember(apps []*app.App) {
+	if p.memoryFile == "" {
+		return
+	}
+
+	for _, a := range apps {
+		c, ok := p.memory[a.Meter]
+		if !ok {
+			c = &app.Client{}
+			p.memory[a.Meter] = c
+		}
+		c.Contract, c.CompanyName, c.Coefficient = a.Contract, a.CompanyName, a.Coefficient
+	}
+
+	if err := p.memory.Save(p.memoryFile); err != nil {
+		log.Printf("the settings are not remembered: %s", err)
+	}
+}
+
+// wait keeps the console window open until Enter is pressed
+func (p *prompter) wait() {
+	fmt.Fprintf(p.w, "press Enter to exit")
+	p.r.ReadString('\n')
+}
+
+// fatal prints the error and exits when Enter is pressed
+func (p *prompter) fatal(err error) {
+	log.Print(err)
+	p.wait()
+	os.Exit(exitCode(err))
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amettod/hourly-meter/internal/app"
+)
+
+// newTestPrompter return prompter reading the answers from the script
+func newTestPrompter(script string, memory app.Registry) (*prompter, *bytes.Buffer) {
+	w := new(bytes.Buffer)
+	if memory == nil {
+		memory = make(app.Registry)
+	}
+
+	return &prompter{r: bufio.NewReader(strings.NewReader(script)), w: w, memory: memory}, w
+}
+
+func Test_input_missing(t *testing.T) {
+	feb := testdata(t, "23456789_feb.html")
+
+	tests := []struct {
+		name string
+		in   input
+		want bool
+	}{
+		{name: "no flags", in: input{coefficient: 1}, want: true},
+		{name: "no contract", in: input{filename: feb, coefficient: 1}, want: true},
+		{name: "contract", in: input{filename: feb, contract: "98765432"}, want: false},
+		{name: "map", in: input{filename: feb, mapping: "23456789=98765432:1"}, want: false},
+		{name: "registry", in: input{filename: feb, registry: "registry.json"}, want: false},
+		{name: "splice", in: input{splice: "segments.csv"}, want: false},
+		{name: "stdin", in: input{filename: "-"}, want: false},
+		{name: "80020 files", in: input{filename: testdata(t, "80020_halfhour.xml")}, want: false},
+		{name: "directory", in: input{filename: filepath.Dir(feb)}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.in.missing(); got != tt.want {
+				t.Errorf("missing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_prompter_input(t *testing.T) {
+	feb := testdata(t, "23456789_feb.html")
+	two := testdata(t, "two_meters.html")
+
+	memory := app.Registry{
+		"23456789": {Contract: "98765432", CompanyName: "OOO STAR", Coefficient: 4000},
+	}
+
+	tests := []struct {
+		name    string
+		in      input
+		memory  app.Registry
+		script  string
+		want    input
+		wantOut string
+		wantErr bool
+	}{
+		{
+			name:   "remembered defaults",
+			in:     input{coefficient: 1},
+			memory: memory,
+			script: feb + "\n\n\n\n",
+			want:   input{filename: feb, meter: "23456789", companyName: "OOO STAR", contract: "98765432", coefficient: 4000},
+		},
+		{
+			name:   "quoted path",
+			in:     input{coefficient: 1},
+			script: `"` + feb + `"` + "\nOOO STAR\n11111111\n2\n",
+			want:   input{filename: feb, meter: "23456789", companyName: "OOO STAR", contract: "11111111", coefficient: 2},
+		},
+		{
+			name:    "bad coefficient",
+			in:      input{coefficient: 1},
+			script:  "'" + feb + "'\nOOO STAR\n11111111\nx\n-1\n40,5\n",
+			want:    input{filename: feb, meter: "23456789", companyName: "OOO STAR", contract: "11111111", coefficient: 40.5},
+			wantOut: "a positive number required\nmeter 23456789: power factory [1]: a positive number required\n",
+		},
+		{
+			name:    "file without contract",
+			in:      input{filename: feb, companyName: "OOO STAR", coefficient: 4000},
+			script:  "\n\n11111111\n\n",
+			want:    input{filename: feb, meter: "23456789", companyName: "OOO STAR", contract: "11111111", coefficient: 4000},
+			wantOut: "No -contract given",
+		},
+		{
+			name:   "several meters",
+			in:     input{coefficient: 1},
+			memory: memory,
+			script: two + "\n\n\n\n\n11111111\n2\n",
+			want: input{filename: two, companyName: "OOO STAR", contract: "11111111", coefficient: 2,
+				mapping: "23456789=98765432:4000,12345678=11111111:2"},
+			wantOut: "meters found: 23456789, 12345678",
+		},
+		{
+			name:    "end of input",
+			in:      input{coefficient: 1},
+			script:  feb + "\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, w := newTestPrompter(tt.script, tt.memory)
+
+			in := tt.in
+			err := p.input(&in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("input() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if in != tt.want {
+				t.Errorf("input() = %+v, want %+v", in, tt.want)
+			}
+			if !strings.Contains(w.String(), tt.wantOut) {
+				t.Errorf("input() printed %q, want %q", w.String(), tt.wantOut)
+			}
+		})
+	}
+}
+
+func Test_prompter_preview(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   bool
+	}{
+		{name: "default", script: "\n", want: true},
+		{name: "yes", script: "да\n", want: true},
+		{name: "no", script: "n\n", want: false},
+		{name: "end of input", script: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apps, err := app.NewAll(testdata(t, "23456789_feb.html"), "OOO STAR", app.Mapping{Contract: "98765432", Coefficient: 4000}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p, w := newTestPrompter(tt.script, nil)
+
+			got, err := p.preview(apps)
+			if err != nil {
+				t.Fatalf("preview() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("preview() = %v, want %v", got, tt.want)
+			}
+			if !strings.Contains(w.String(), "meter 23456789, contract 98765432:") {
+				t.Errorf("preview() printed %q", w.String())
+			}
+		})
+	}
+}
+
+func Test_prompter_remember(t *testing.T) {
+	feb := testdata(t, "23456789_feb.html")
+
+	apps, err := app.NewAll(feb, "OOO STAR", app.Mapping{Contract: "98765432", Coefficient: 4000}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, _ := newTestPrompter("", nil)
+	p.memoryFile = filepath.Join(t.TempDir(), "hourly-meter", "meters.json")
+	p.remember(apps)
+
+	memory, err := app.LoadRegistry(p.memoryFile)
+	if err != nil {
+		t.Fatalf("LoadRegistry() error = %v", err)
+	}
+
+	c, ok := memory["23456789"]
+	if !ok || c.Contract != "98765432" || c.CompanyName != "OOO STAR" || c.Coefficient != 4000 {
+		t.Errorf("remember() = %+v, want the settings of the meter", c)
+	}
+
+	// the remembered settings are the defaults of the next run
+	p, _ = newTestPrompter(feb+"\n\n\n\n", memory)
+	in := input{coefficient: 1}
+	if err := p.input(&in); err != nil {
+		t.Fatalf("input() error = %v", err)
+	}
+	if in.contract != "98765432" || in.coefficient != 4000 {
+		t.Errorf("input() = %+v, want the remembered settings", in)
+	}
+}
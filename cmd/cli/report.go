@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/amettod/hourly-meter/internal/app"
+)
+
+// runReport prints the monthly summary of the meters without writing the 80020 files
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	in := addInput(fs)
+	format := fs.String("format", "txt", "output format: txt, html or json")
+	output := fs.String("o", "", "write the summary to the file instead of stdout")
+
+	fs.Usage = commandUsage(fs, "report", "", "prints the totals, gaps, estimated hours, zones, capacity and daily maximums of each meter")
+	fs.Parse(args)
+
+	apps, err := in.load()
+	if err != nil {
+		fatal(err)
+	}
+
+	report, err := app.NewReport(apps)
+	if err != nil {
+		fatal(err)
+	}
+
+	var write func(w io.Writer) error
+	switch *format {
+	case "txt":
+		write = report.WriteText
+	case "html":
+		write = report.WriteHTML
+	case "json":
+		write = report.WriteJSON
+	default:
+		fmt.Fprintf(os.Stderr, "bad format %s\n", *format)
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	w := os.Stdout
+	if *output != "" {
+		w, err = os.Create(*output)
+		if err != nil {
+			fatal(err)
+		}
+		defer w.Close()
+	}
+
+	err = write(w)
+	if err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/amettod/hourly-meter/internal/app"
+)
+
+// runValidate checks the meter exports without writing any file
+// and exits with exitProblems if any problem is found
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	in := addInput(fs)
+	limits := addLimits(fs)
+	allowEstimated := fs.Bool("allow-estimated", false, "don't report the estimated hours")
+
+	fs.Usage = commandUsage(fs, "validate", "", fmt.Sprintf("checks the gaps, estimated hours, merge conflicts, anomalies and register readings,\nexits with %d if any problem is found", exitProblems))
+	fs.Parse(args)
+
+	apps, err := in.load()
+	if err != nil {
+		fatal(err)
+	}
+
+	report, err := app.NewReport(apps)
+	if err != nil {
+		fatal(err)
+	}
+
+	var problems int
+	problem := func(meter, kind, text string) {
+		problems++
+		fmt.Printf("%s\t%s\t%s\n", meter, kind, text)
+	}
+
+	for i, a := range apps {
+		m := report.Meters[i]
+
+		if m.Gaps != 0 {
+			problem(a.Meter, "gaps", fmt.Sprintf("%d hours of the month without a value", m.Gaps))
+		}
+		if m.Estimated != 0 && !*allowEstimated {
+			problem(a.Meter, "estimated", fmt.Sprintf("%d hours with a note or an incomplete period", m.Estimated))
+		}
+		for _, c := range a.Conflicts {
+			problem(a.Meter, "conflict", c.String())
+		}
+		if a.Reconciliation != nil && !a.Reconciliation.OK() {
+			problem(a.Meter, "registers", a.Reconciliation.String())
+		}
+		for _, anomaly := range a.Analyze(*limits) {
+			problem(a.Meter, "anomaly", anomaly.String())
+		}
+	}
+
+	if problems != 0 {
+		fmt.Printf("%d problems\n", problems)
+		os.Exit(exitProblems)
+	}
+
+	fmt.Printf("ok: %d meters\n", len(apps))
+}
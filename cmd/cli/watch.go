@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/amettod/hourly-meter/internal/app"
+)
+
+// runWatch polls the inbox and converts the new exports until SIGINT or SIGTERM
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	registryFile := fs.String("registry", "", "*.json registry of the meters: contract, company name, coefficients and recipient by serial number")
+	inbox := fs.String("inbox", "inbox", "directory polled for new *.html exports")
+	outbox := fs.String("outbox", "outbox", "directory of the converted files, each file goes to recipient/accountpoint/80020-MM-YYYY")
+	archive := fs.String("archive", "archive", "directory of the converted exports")
+	failed := fs.String("errors", "errors", "directory of the failed exports and their *.log files")
+	interval := fs.Duration("interval", 10*time.Second, "time between the polls of the inbox")
+	settle := fs.Duration("settle", 5*time.Second, "an export is converted when it hasn't changed for this time")
+	workers := fs.Int("workers", runtime.NumCPU(), "files converted at the same time")
+
+	fs.Usage = commandUsage(fs, "watch", "", "converts the exports saved into the inbox with the registry settings until SIGINT or SIGTERM,\nthe file being converted is finished before the exit")
+	fs.Parse(args)
+
+	if *registryFile == "" {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	registry, err := app.LoadRegistry(*registryFile)
+	if err != nil {
+		fatal(err)
+	}
+
+	w, err := app.NewWatcher(registry, *inbox, *outbox, *archive, *failed)
+	if err != nil {
+		fatal(err)
+	}
+	w.Settle = *settle
+	w.Workers = *workers
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("watching %s every %s", *inbox, *interval)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		results, err := w.Poll(time.Now())
+		for _, b := range results {
+			if b.Err != nil {
+				log.Printf("%s: failed: %s", b.Filename, b.Err)
+				continue
+			}
+			log.Printf("%s: %v %.2f kWh to %s", b.Filename, b.Meters, b.Total, b.Dir)
+		}
+		if err != nil {
+			log.Print(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Print("stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
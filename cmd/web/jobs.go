@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/amettod/hourly-meter/internal/app"
+)
+
+// workspace keeps the files of each conversion in its own job directory until the retention period ends
+type workspace struct {
+	dir       string
+	retention time.Duration
+	maxUpload int64 // the largest form with the uploaded files in bytes
+}
+
+// jobs the workspace of the conversions
+var jobs = &workspace{}
+
+// newJob creates a job directory and return its id
+func (ws *workspace) newJob() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+
+	err = os.MkdirAll(filepath.Join(ws.dir, id), 0766)
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// jobDir return the directory of the job
+func (ws *workspace) jobDir(id string) string {
+	return filepath.Join(ws.dir, id)
+}
+
+// cleanup removes the jobs created before the retention period
+func (ws *workspace) cleanup(now time.Time) {
+	entries, err := os.ReadDir(ws.dir)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !e.IsDir() || !jobID.MatchString(e.Name()) || now.Sub(info.ModTime()) < ws.retention {
+			continue
+		}
+
+		err = os.RemoveAll(filepath.Join(ws.dir, e.Name()))
+		if err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// keepClean removes the expired jobs every interval
+func (ws *workspace) keepClean(interval time.Duration) {
+	for now := range time.Tick(interval) {
+		ws.cleanup(now)
+	}
+}
+
+// maxMemory the part of the form kept in memory, the rest of the files goes to temporary files
+const maxMemory = 10 << 20
+
+// saveRequest streams the uploaded form into the job directory so that it is converted after the preview
+func (ws *workspace) saveRequest(id string, w http.ResponseWriter, r *http.Request) error {
+	f, err := os.Create(filepath.Join(ws.jobDir(id), "request"))
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, http.MaxBytesReader(w, r.Body, ws.maxUpload))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(ws.jobDir(id), "content_type"), []byte(r.Header.Get("Content-Type")), 0644)
+}
+
+// loadRequest return the request with the parsed multipart form kept by saveRequest
+func (ws *workspace) loadRequest(id string) (*http.Request, error) {
+	contentType, err := os.ReadFile(filepath.Join(ws.jobDir(id), "content_type"))
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Join(ws.jobDir(id), "request"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := http.NewRequest(http.MethodPost, "/run", f)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", string(contentType))
+
+	err = r.ParseMultipartForm(maxMemory)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// jobRequest return the request with the parsed multipart form and the id of its job, the form confirmed
+// on the preview page is replaced by the kept form of its job with the fields of the preview,
+// the status is the HTTP status of the error
+func jobRequest(w http.ResponseWriter, r *http.Request) (*http.Request, string, int, error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		id, err := jobs.newJob()
+		if err != nil {
+			return nil, "", http.StatusInternalServerError, err
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, jobs.maxUpload)
+		err = r.ParseMultipartForm(maxMemory)
+		if err != nil {
+			return nil, "", http.StatusBadRequest, err
+		}
+
+		return r, id, http.StatusOK, nil
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		return nil, "", http.StatusBadRequest, err
+	}
+
+	id := r.PostForm.Get("job")
+	if !jobID.MatchString(id) {
+		return nil, "", http.StatusBadRequest, errors.New("bad job")
+	}
+
+	if _, err := os.Stat(filepath.Join(jobs.jobDir(id), "request")); err != nil {
+		return nil, "", http.StatusNotFound, fmt.Errorf("job %s has expired: %w", id, err)
+	}
+
+	kept, err := jobs.loadRequest(id)
+	if err != nil {
+		return nil, "", http.StatusBadRequest, err
+	}
+
+	for _, key := range previewFields {
+		if v, ok := r.PostForm[key]; ok {
+			kept.PostForm[key] = v
+		}
+	}
+
+	return kept, id, http.StatusOK, nil
+}
+
+// previewFields the fields of the form which may be adjusted on the preview page
+var previewFields = []string{"contract", "name", "coefficient", "map"}
+
+// jobID matches the name of a job directory
+var jobID = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// jobPath matches /jobs/<id>/80020-MM-YYYY.zip and /jobs/<id>/80020-MM-YYYY/ with the summary or the exported profile files
+var jobPath = regexp.MustCompile(`^/jobs/([0-9a-f]{32})/(80020-\d{2}-\d{4})(\.zip|/(summary\.(txt|html|json)|profile_[\w-]+\.(csv|xlsx)))$`)
+
+// getJob serves the zip of the converted month and its summary and spreadsheets
+func getJob(w http.ResponseWriter, r *http.Request) {
+	m := jobPath.FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	dirName := filepath.Join(jobs.jobDir(m[1]), m[2])
+	if _, err := os.Stat(dirName); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if m[3] != ".zip" {
+		http.ServeFile(w, r, filepath.Join(dirName, m[4]))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+m[2]+`.zip"`)
+
+	err := app.WriteArchive(w, dirName, app.ArchiveZip)
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"bytes"
 	"embed"
+	"encoding/base64"
 	"errors"
 	"flag"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/amettod/hourly-meter/internal/app"
 )
@@ -20,10 +25,22 @@ var pages embed.FS
 
 func main() {
 	addr := flag.Int("addr", 8008, "server port address")
+	flag.StringVar(&jobs.dir, "workspace", filepath.Join(os.TempDir(), "hourly-meter-jobs"), "directory of the job workspaces with the converted files")
+	flag.DurationVar(&jobs.retention, "retention", 24*time.Hour, "time the converted files are kept for the download")
+	flag.Int64Var(&jobs.maxUpload, "max-upload", 100<<20, "largest form with the uploaded files in bytes")
 	flag.Parse()
 
+	err := os.MkdirAll(jobs.dir, 0766)
+	if err != nil {
+		log.Fatal(err)
+	}
+	jobs.cleanup(time.Now())
+	go jobs.keepClean(time.Minute)
+
 	http.Handle("/", allowMethod(setForm, http.MethodGet))
+	http.Handle("/preview", allowMethod(previewApp, http.MethodPost))
 	http.Handle("/run", allowMethod(runApp, http.MethodPost))
+	http.Handle("/jobs/", allowMethod(getJob, http.MethodGet))
 
 	log.Printf("go to http://localhost:8008/")
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", *addr), nil))
@@ -38,62 +55,571 @@ func setForm(w http.ResponseWriter, r *http.Request) {
 	templateParse(w, nil, "form_page.tmpl", "base_layout.tmpl")
 }
 
-func runApp(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(10 << 20)
+// previewApp shows the meters of the uploaded exports and keeps the form in a job
+// until the contract and the coefficient are confirmed
+func previewApp(w http.ResponseWriter, r *http.Request) {
+	id, err := jobs.newJob()
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = jobs.saveRequest(id, w, r)
 	if err != nil {
 		httpError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	file, header, err := r.FormFile("filename")
+	r, err = jobs.loadRequest(id)
 	if err != nil {
 		httpError(w, http.StatusBadRequest, err)
 		return
 	}
-	defer file.Close()
+	defer r.MultipartForm.RemoveAll()
 
-	f, err := os.CreateTemp("", header.Filename)
+	apps, status, err := loadApps(r)
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, err)
+		httpError(w, status, err)
 		return
 	}
-	defer os.Remove(f.Name())
 
-	_, err = io.Copy(f, file)
+	report, err := app.NewReport(apps)
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, err)
+		httpError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	contract := r.PostForm.Get("contract")
-	name := r.PostForm.Get("name")
-	meter := r.PostForm.Get("meter")
-	coefficient, err := strconv.ParseFloat(r.PostForm.Get("coefficient"), 64)
+	limits := formLimits(r)
+
+	var meters []map[string]interface{}
+	for i, a := range apps {
+		buff := new(bytes.Buffer)
+		err = a.Chart(0).WriteSVG(buff, 800, 240)
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		var period string
+		if len(a.Rows) != 0 {
+			period = a.Rows[0].Date.Add(-time.Hour).Format("02.01.2006 15:04") + " - " + a.Rows[len(a.Rows)-1].Date.Format("02.01.2006 15:04")
+		}
+
+		meters = append(meters, map[string]interface{}{
+			"Report":       report.Meters[i],
+			"CompanyName":  a.CompanyName,
+			"Period":       period,
+			"Coefficients": a.CoefficientUsage(),
+			"Conflicts":    a.Conflicts,
+			"Anomalies":    a.Analyze(limits),
+			"Chart":        template.HTML(buff.String()),
+		})
+	}
+
+	preview := map[string]interface{}{
+		"Job":         id,
+		"Month":       fmt.Sprintf("%02d.%d", report.Month, report.Year),
+		"Meters":      meters,
+		"Contract":    r.PostForm.Get("contract"),
+		"Name":        r.PostForm.Get("name"),
+		"Coefficient": r.PostForm.Get("coefficient"),
+		"Map":         r.PostForm.Get("map"),
+	}
+
+	templateParse(w, preview, "preview_page.tmpl", "base_layout.tmpl")
+}
+
+func runApp(w http.ResponseWriter, r *http.Request) {
+	r, id, status, err := jobRequest(w, r)
+	if err != nil {
+		httpError(w, status, err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	apps, status, err := loadApps(r)
+	if err != nil {
+		httpError(w, status, err)
+		return
+	}
+
+	tariff, err := formTariff(r)
 	if err != nil {
 		httpError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	app, err := app.New(f.Name(), contract, name, meter, coefficient)
+	powerFactor, err := formPowerFactor(r)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	limits := formLimits(r)
+
+	_, err = app.RunAllIn(jobs.jobDir(id), apps)
 	if err != nil {
 		httpError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	err = app.Run()
+	var total float64
+	var values int
+	var meters []map[string]interface{}
+	for _, a := range apps {
+		total += a.Total
+		values += len(a.Rows)
+
+		tanPhi := a.TanPhi(powerFactor)
+
+		buff := new(bytes.Buffer)
+		err = tanPhi.WriteCSV(buff)
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		var costs []*app.Cost
+		var cheapest *app.Cost
+		if tariff != nil {
+			costs = a.Costs(tariff)
+			cheapest = app.Cheapest(costs)
+		}
+
+		var coefficientUsage []*app.CoefficientUse
+		if a.Coefficients != nil {
+			coefficientUsage = a.CoefficientUsage()
+		}
+
+		meters = append(meters, map[string]interface{}{
+			"Costs":        costs,
+			"Cheapest":     cheapest,
+			"Meter":        a.Meter,
+			"Export":       a.Export,
+			"Total":        fmt.Sprintf("%.2f", a.Total),
+			"Values":       len(a.Rows),
+			"Zones":        a.Zones,
+			"Capacity":     a.Capacity,
+			"CapacityErr":  a.CapacityErr,
+			"Reconcile":    a.Reconciliation,
+			"Coefficients": coefficientUsage,
+			"Anomalies":    a.Analyze(limits),
+			"TanPhi":       tanPhi,
+			"TanPhiCSV":    template.URL("data:text/csv;base64," + base64.StdEncoding.EncodeToString(buff.Bytes())),
+		})
+	}
+
+	result := map[string]interface{}{
+		"Total":   fmt.Sprintf("%.2f", total),
+		"Values":  fmt.Sprintf("%d", values),
+		"Meters":  meters,
+		"Output":  "/jobs/" + id + "/" + app.DirName(apps[0].Month, apps[0].Year),
+		"Expires": time.Now().Add(jobs.retention).Format("02.01.2006 15:04"),
+	}
+
+	if len(apps) == 1 {
+		result["Conflicts"] = apps[0].Conflicts
+	}
+
+	// the page is rendered before the status so that an error is reported instead of it
+	page, err := templateRender(result, "result_page.tmpl", "base_layout.tmpl")
 	if err != nil {
 		httpError(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
+	w.Write(page.Bytes())
+}
+
+// loadApps return the apps of the uploaded exports with the settings of the form,
+// the status is the HTTP status of the error
+func loadApps(r *http.Request) ([]*app.App, int, error) {
+	var filenames []string
+
+	for _, header := range r.MultipartForm.File["filename"] {
+		filename, err := saveUpload(header)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		defer removeUpload(filename)
+
+		filenames = append(filenames, filename)
+	}
+
+	if len(filenames) == 0 {
+		return nil, http.StatusBadRequest, http.ErrMissingFile
+	}
+
+	contract := r.PostForm.Get("contract")
+	name := r.PostForm.Get("name")
+	meter := r.PostForm.Get("meter")
+	if meter != "" && !app.ValidName(meter) {
+		return nil, http.StatusBadRequest, fmt.Errorf("bad meter %q", meter)
+	}
+	coefficient, err := strconv.ParseFloat(r.PostForm.Get("coefficient"), 64)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	mappings, err := app.ParseMappings(r.PostForm.Get("map"))
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	var apps []*app.App
+
+	spliced, err := formSpliced(r, contract, name, meter)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	if spliced != nil {
+		apps = append(apps, spliced)
+	} else if app.IsXML(filenames) {
+		apps, err = app.NewXML(filenames, name, app.Mapping{Contract: contract, Coefficient: coefficient}, mappings)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+	} else if len(filenames) > 1 {
+		a, err := app.NewMerged(filenames, contract, name, meter, coefficient)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		for _, c := range a.Conflicts {
+			log.Printf("conflict: %s", c)
+		}
+		apps = append(apps, a)
+	} else if meter != "" {
+		a, err := app.New(filenames[0], contract, name, meter, coefficient)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		apps = append(apps, a)
+	} else {
+		apps, err = app.NewAll(filenames[0], name, app.Mapping{Contract: contract, Coefficient: coefficient}, mappings)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+	}
+
+	schedule, err := formSchedule(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	calendar, err := formCalendar(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	peakHours, err := formPeakHours(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	coefficients, err := formCoefficients(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	for _, a := range apps {
+		a.Schedule = schedule
+		a.Calendar = calendar
+		a.PeakHours = peakHours
+		if c, ok := coefficients[a.Meter]; ok {
+			a.Coefficients = c
+		}
+	}
+
+	export, err := app.NewExport(r.PostForm["export"], r.PostForm.Get("layout"))
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	for _, a := range apps {
+		a.Export = export
+	}
+
+	err = formReconcile(r, apps)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	groups, err := app.ParseGroups(r.PostForm.Get("group"))
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	for _, g := range groups {
+		a, err := app.NewGroup(g, apps)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		apps = append(apps, a)
+	}
 
-	result := map[string]string{
-		"Total":  fmt.Sprintf("%.2f", app.Total),
-		"Values": fmt.Sprintf("%d", len(app.Rows)),
+	return apps, http.StatusOK, nil
+}
+
+// formLimits return the anomaly limits of the form
+func formLimits(r *http.Request) app.Limits {
+	limits := app.DefaultLimits()
+	limits.ConsumptionOnly = r.PostForm.Get("consumption_only") != ""
+
+	return limits
+}
+
+// formPowerFactor return tan φ limits of the form, empty fields keep the default values
+func formPowerFactor(r *http.Request) (app.PowerFactor, error) {
+	pf := app.DefaultPowerFactor()
+
+	var err error
+
+	if v := r.PostForm.Get("tan_limit"); v != "" {
+		pf.Limit, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return pf, err
+		}
+	}
+
+	if v := r.PostForm.Get("peak_start"); v != "" {
+		pf.PeakStart, err = strconv.Atoi(v)
+		if err != nil {
+			return pf, err
+		}
+	}
+
+	if v := r.PostForm.Get("peak_end"); v != "" {
+		pf.PeakEnd, err = strconv.Atoi(v)
+		if err != nil {
+			return pf, err
+		}
+	}
+
+	return pf, nil
+}
+
+// formSchedule return the tariff zones of the form, the uploaded schedule file takes precedence
+func formSchedule(r *http.Request) (*app.Schedule, error) {
+	if headers := r.MultipartForm.File["zones_file"]; len(headers) != 0 {
+		filename, err := saveUpload(headers[0])
+		if err != nil {
+			return nil, err
+		}
+		defer removeUpload(filename)
+
+		return app.LoadSchedule(filename)
 	}
 
-	templateParse(w, result, "result_page.tmpl", "base_layout.tmpl")
+	return app.LoadSchedule(r.PostForm.Get("zones"))
+}
+
+// formTariff return the uploaded tariff, nil if there is no file
+func formTariff(r *http.Request) (*app.Tariff, error) {
+	headers := r.MultipartForm.File["tariff_file"]
+	if len(headers) == 0 {
+		return nil, nil
+	}
+
+	filename, err := saveUpload(headers[0])
+	if err != nil {
+		return nil, err
+	}
+	defer removeUpload(filename)
+
+	return app.LoadTariff(filename)
+}
+
+// formCalendar return the uploaded production calendar, nil if there are no files
+func formCalendar(r *http.Request) (*app.Calendar, error) {
+	headers := r.MultipartForm.File["calendar_file"]
+	if len(headers) == 0 {
+		return nil, nil
+	}
+
+	var filenames []string
+	for _, header := range headers {
+		filename, err := saveUpload(header)
+		if err != nil {
+			return nil, err
+		}
+		defer removeUpload(filename)
+
+		filenames = append(filenames, filename)
+	}
+
+	return app.LoadCalendar(filenames...)
+}
+
+// formPeakHours return the uploaded planned peak hours, nil if there is no file
+func formPeakHours(r *http.Request) (app.PeakHours, error) {
+	headers := r.MultipartForm.File["peak_hours_file"]
+	if len(headers) == 0 {
+		return nil, nil
+	}
+
+	filename, err := saveUpload(headers[0])
+	if err != nil {
+		return nil, err
+	}
+	defer removeUpload(filename)
+
+	return app.LoadPeakHours(filename)
+}
+
+// formCoefficients return the uploaded coefficient history, nil if there is no file
+func formCoefficients(r *http.Request) (map[string][]*app.CoefficientPeriod, error) {
+	headers := r.MultipartForm.File["coefficients_file"]
+	if len(headers) == 0 {
+		return nil, nil
+	}
+
+	filename, err := saveUpload(headers[0])
+	if err != nil {
+		return nil, err
+	}
+	defer removeUpload(filename)
+
+	return app.LoadCoefficients(filename)
+}
+
+// formReconcile compares the profiles with the register readings of the form
+// and corrects them if asked, the uploaded readings file takes precedence
+func formReconcile(r *http.Request, apps []*app.App) error {
+	registers := make(app.Registers)
+
+	if headers := r.MultipartForm.File["registers_file"]; len(headers) != 0 {
+		filename, err := saveUpload(headers[0])
+		if err != nil {
+			return err
+		}
+		defer removeUpload(filename)
+
+		registers, err = app.LoadRegisters(filename)
+		if err != nil {
+			return err
+		}
+	} else if start, end := r.PostForm.Get("register_start"), r.PostForm.Get("register_end"); start != "" || end != "" {
+		if len(apps) != 1 {
+			return errors.New("register readings fields are for a single meter, upload the readings file")
+		}
+
+		register, err := app.ParseRegister(start, end)
+		if err != nil {
+			return err
+		}
+		registers[apps[0].Meter] = register
+	}
+
+	tolerance := 0.5
+	if v := r.PostForm.Get("tolerance"); v != "" {
+		var err error
+		tolerance, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, a := range apps {
+		register, ok := registers[a.Meter]
+		if !ok {
+			continue
+		}
+
+		rec, err := a.Reconcile(register, tolerance)
+		if err != nil {
+			return err
+		}
+
+		if r.PostForm.Get("correct") != "" && !rec.OK() {
+			err = a.Correct(rec)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// formSpliced return the accountpoint spliced from the uploaded segments file, nil if there is no file,
+// the filenames of the segments are the names of the uploaded exports
+func formSpliced(r *http.Request, contract, name, code string) (*app.App, error) {
+	headers := r.MultipartForm.File["segments_file"]
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	if code == "" {
+		return nil, errors.New("meter serial number is required as the accountpoint code of the segments")
+	}
+
+	dir, err := os.MkdirTemp("", "segments")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	var names []string
+	for _, header := range r.MultipartForm.File["filename"] {
+		names = append(names, filepath.Base(header.Filename))
+	}
+
+	for _, header := range append(r.MultipartForm.File["filename"], headers[0]) {
+		err = copyUpload(header, filepath.Join(dir, filepath.Base(header.Filename)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	segments, err := app.LoadUploadedSegments(filepath.Join(dir, filepath.Base(headers[0].Filename)), names)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.NewSpliced(segments, contract, name, code)
+}
+
+// copyUpload copies the uploaded file to the named file
+func copyUpload(header *multipart.FileHeader, filename string) error {
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, file)
+
+	return err
+}
+
+// saveUpload copies the uploaded file to a temporary directory under its own name
+// so that the reports show the name of the export, and returns the file name
+func saveUpload(header *multipart.FileHeader) (string, error) {
+	dir, err := os.MkdirTemp("", "upload")
+	if err != nil {
+		return "", err
+	}
+
+	filename := filepath.Join(dir, filepath.Base(header.Filename))
+
+	err = copyUpload(header, filename)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	return filename, nil
+}
+
+// removeUpload removes the uploaded file with its temporary directory
+func removeUpload(filename string) {
+	os.RemoveAll(filepath.Dir(filename))
 }
 
 func allowMethod(next http.HandlerFunc, method string) http.Handler {
@@ -123,14 +649,27 @@ func addTemplatesPrefix(file []string) []string {
 }
 
 func templateParse(w http.ResponseWriter, data interface{}, files ...string) {
-	temp, err := template.ParseFS(pages, addTemplatesPrefix(files)...)
+	page, err := templateRender(data, files...)
 	if err != nil {
 		httpError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	err = temp.Execute(w, data)
+	w.Write(page.Bytes())
+}
+
+// templateRender return the page of the templates filled with data
+func templateRender(data interface{}, files ...string) (*bytes.Buffer, error) {
+	temp, err := template.ParseFS(pages, addTemplatesPrefix(files)...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := new(bytes.Buffer)
+	err = temp.Execute(page, data)
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, err)
+		return nil, err
 	}
+
+	return page, nil
 }
@@ -0,0 +1,220 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Kinds of anomalies
+const (
+	AnomalySpike  = "spike"
+	AnomalyZeros  = "zeros"
+	AnomalyPMinus = "p-minus"
+	AnomalyRatio  = "ratio"
+	AnomalyFrozen = "frozen"
+)
+
+// Limits of the anomaly analysis, a zero value disables the check
+type Limits struct {
+	Sigma           float64 // deviation from the same weekday and hour in standard deviations
+	ZeroHours       int     // consecutive working hours with zero P+
+	FrozenHours     int     // consecutive hours with the same non-zero P+, 1 and 2 report any repeated hour
+	MinRatio        float64 // lower limit of Q+/P+
+	MaxRatio        float64 // upper limit of Q+/P+
+	WorkStart       int     // first working hour of the day
+	WorkEnd         int     // hour when the working day ends
+	ConsumptionOnly bool    // P- should be zero
+}
+
+// DefaultLimits return limits suitable for most sites
+func DefaultLimits() Limits {
+	return Limits{
+		Sigma:       3,
+		ZeroHours:   4,
+		FrozenHours: 6,
+		WorkStart:   8,
+		WorkEnd:     18,
+	}
+}
+
+// Anomaly a suspicious pattern of the profile
+type Anomaly struct {
+	Kind string
+	From time.Time
+	To   time.Time
+	Text string
+}
+
+// String return an anomaly description
+func (a *Anomaly) String() string {
+	return fmt.Sprintf("%s %s - %s: %s", a.Kind,
+		a.From.Format("02.01.2006 15:04"), a.To.Format("02.01.2006 15:04"), a.Text)
+}
+
+// Analyze return anomalies of the scaled hourly profile
+func (a *App) Analyze(l Limits) []*Anomaly {
+	var anomalies []*Anomaly
+
+	if l.Sigma > 0 {
+		anomalies = append(anomalies, a.spikes(l.Sigma)...)
+	}
+
+	if l.ZeroHours > 0 {
+		for _, r := range runs(a.Rows, func(i int) bool {
+			return a.Rows[i].PPlus == 0 && l.isWorkingHour(a.Calendar, a.Rows[i].Date.Add(-time.Hour))
+		}) {
+			if r[1]-r[0]+1 >= l.ZeroHours {
+				anomalies = append(anomalies, a.anomaly(AnomalyZeros, r, fmt.Sprintf("%d working hours without consumption", r[1]-r[0]+1)))
+			}
+		}
+	}
+
+	if l.ConsumptionOnly {
+		for _, r := range runs(a.Rows, func(i int) bool { return a.Rows[i].PMinus != 0 }) {
+			var sum float64
+			for i := r[0]; i <= r[1]; i++ {
+				sum += a.Rows[i].PMinus * a.coefficient(a.Rows[i])
+			}
+			anomalies = append(anomalies, a.anomaly(AnomalyPMinus, r, fmt.Sprintf("P- %.2f kWh on a consumption-only site", sum)))
+		}
+	}
+
+	if l.MinRatio > 0 || l.MaxRatio > 0 {
+		for _, r := range runs(a.Rows, func(i int) bool {
+			row := a.Rows[i]
+			if row.PPlus == 0 {
+				return false
+			}
+			ratio := row.QPlus / row.PPlus
+			return ratio < l.MinRatio || (l.MaxRatio > 0 && ratio > l.MaxRatio)
+		}) {
+			anomalies = append(anomalies, a.anomaly(AnomalyRatio, r, fmt.Sprintf("Q+/P+ outside %.2f - %.2f for %d hours", l.MinRatio, l.MaxRatio, r[1]-r[0]+1)))
+		}
+	}
+
+	if l.FrozenHours > 0 {
+		for _, r := range runs(a.Rows, func(i int) bool {
+			return i > 0 && a.Rows[i].PPlus != 0 && a.Rows[i].PPlus == a.Rows[i-1].PPlus
+		}) {
+			// the first hour of the repeated value doesn't match its predecessor
+			r[0]--
+			if r[1]-r[0]+1 >= l.FrozenHours {
+				anomalies = append(anomalies, a.anomaly(AnomalyFrozen, r, fmt.Sprintf("P+ %.2f kWh repeated %d hours", a.Rows[r[0]].PPlus*a.coefficient(a.Rows[r[0]]), r[1]-r[0]+1)))
+			}
+		}
+	}
+
+	return anomalies
+}
+
+// isWorkingHour return true if the hour starting at t is a working hour of the working day
+func (l Limits) isWorkingHour(cal *Calendar, t time.Time) bool {
+	if !cal.IsWorkingDay(t) {
+		return false
+	}
+
+	return t.Hour() >= l.WorkStart && t.Hour() < l.WorkEnd
+}
+
+// spikes return hours beyond sigma standard deviations of the other hours of the same weekday and hour,
+// holidays are compared with Sundays and working weekends with Mondays
+func (a *App) spikes(sigma float64) []*Anomaly {
+	key := func(r *Row) [2]int {
+		start := r.Date.Add(-time.Hour)
+		return [2]int{int(a.Calendar.profileDay(start)), start.Hour()}
+	}
+
+	groups := make(map[[2]int][]int)
+	for i, r := range a.Rows {
+		groups[key(r)] = append(groups[key(r)], i)
+	}
+
+	var anomalies []*Anomaly
+
+	for i, r := range a.Rows {
+		group := groups[key(r)]
+
+		// the hour itself is left out so that it doesn't hide in its own deviation
+		var others []float64
+		for _, j := range group {
+			if j != i {
+				others = append(others, a.Rows[j].PPlus*a.coefficient(a.Rows[j]))
+			}
+		}
+		if len(others) < 2 {
+			continue
+		}
+
+		// a month gives only four or five hours of the same weekday, so their deviation
+		// is not trusted below a fifth of the mean, an ordinary hour stays within 3 sigma
+		mean, std := meanStd(others)
+		std = math.Max(std, mean/5)
+		p := r.PPlus * a.coefficient(r)
+		if std == 0 || math.Abs(p-mean) <= sigma*std {
+			continue
+		}
+
+		anomalies = append(anomalies, a.anomaly(AnomalySpike, [2]int{i, i},
+			fmt.Sprintf("P+ %.2f kWh, usual %.2f ± %.2f kWh", p, mean, std)))
+	}
+
+	return anomalies
+}
+
+// anomaly return Anomaly for the rows run
+func (a *App) anomaly(kind string, run [2]int, text string) *Anomaly {
+	return &Anomaly{
+		Kind: kind,
+		From: a.Rows[run[0]].Date.Add(-time.Hour),
+		To:   a.Rows[run[1]].Date,
+		Text: text,
+	}
+}
+
+// runs return the first and the last index of each run of consecutive hours matching the condition
+func runs(rows []*Row, match func(i int) bool) [][2]int {
+	var result [][2]int
+
+	start := -1
+
+	for i := range rows {
+		if match(i) && (start == -1 || rows[i].Date.Sub(rows[i-1].Date) == time.Hour) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+
+		if start != -1 {
+			result = append(result, [2]int{start, i - 1})
+			start = -1
+		}
+
+		if match(i) {
+			start = i
+		}
+	}
+
+	if start != -1 {
+		result = append(result, [2]int{start, len(rows) - 1})
+	}
+
+	return result
+}
+
+// meanStd return the mean and the sample standard deviation of at least two values
+func meanStd(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+
+	return mean, math.Sqrt(sq / float64(len(values)-1))
+}
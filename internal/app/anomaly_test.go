@@ -0,0 +1,153 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+// hourlyRows return rows of consecutive hours starting at 01.02.2020 00:00 with P+ from values
+func hourlyRows(values []float64) []*Row {
+	var rows []*Row
+	for i, v := range values {
+		rows = append(rows, &Row{
+			ID:    i + 1,
+			PPlus: v,
+			QPlus: v / 2,
+			Date:  time.Date(2020, 2, 1, 1, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	return rows
+}
+
+// wavyValues return n hourly values which differ from hour to hour and from week to week
+func wavyValues(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = 1 + float64(i%24)/10 + float64(i/168)/100
+	}
+
+	return values
+}
+
+func TestApp_Analyze(t *testing.T) {
+	tests := []struct {
+		name   string
+		rows   func() []*Row
+		limits Limits
+		want   string
+		wantAt time.Time
+	}{
+		{
+			name: "spike",
+			rows: func() []*Row {
+				values := wavyValues(29 * 24)
+				values[24*10+12] = 50 // 11.02.2020 12:00
+				return hourlyRows(values)
+			},
+			limits: Limits{Sigma: 3},
+			want:   AnomalySpike,
+			wantAt: time.Date(2020, 2, 11, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "zeros in working hours",
+			rows: func() []*Row {
+				values := wavyValues(29 * 24)
+				for i := 24*3 + 8; i < 24*3+14; i++ { // 04.02.2020 is tuesday
+					values[i] = 0
+				}
+				return hourlyRows(values)
+			},
+			limits: Limits{ZeroHours: 4, WorkStart: 8, WorkEnd: 18},
+			want:   AnomalyZeros,
+			wantAt: time.Date(2020, 2, 4, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "p minus",
+			rows: func() []*Row {
+				rows := hourlyRows(wavyValues(48))
+				rows[30].PMinus = 0.5
+				return rows
+			},
+			limits: Limits{ConsumptionOnly: true},
+			want:   AnomalyPMinus,
+			wantAt: time.Date(2020, 2, 2, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "ratio",
+			rows: func() []*Row {
+				rows := hourlyRows(wavyValues(48))
+				rows[5].QPlus = rows[5].PPlus * 2
+				return rows
+			},
+			limits: Limits{MaxRatio: 1},
+			want:   AnomalyRatio,
+			wantAt: time.Date(2020, 2, 1, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "frozen",
+			rows: func() []*Row {
+				values := wavyValues(48)
+				for i := 20; i < 28; i++ {
+					values[i] = 7
+				}
+				return hourlyRows(values)
+			},
+			limits: Limits{FrozenHours: 6},
+			want:   AnomalyFrozen,
+			wantAt: time.Date(2020, 2, 1, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "frozen two hours of the lowest limit",
+			rows: func() []*Row {
+				values := wavyValues(48)
+				values[31] = values[30]
+				return hourlyRows(values)
+			},
+			limits: Limits{FrozenHours: 1},
+			want:   AnomalyFrozen,
+			wantAt: time.Date(2020, 2, 2, 6, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &App{Coefficient: 1, Rows: tt.rows()}
+
+			got := a.Analyze(tt.limits)
+			if len(got) != 1 {
+				t.Fatalf("Analyze() = %v, want one %s", got, tt.want)
+			}
+			if got[0].Kind != tt.want || !got[0].From.Equal(tt.wantAt) {
+				t.Errorf("Analyze() = %v, want %s at %v", got[0], tt.want, tt.wantAt)
+			}
+		})
+	}
+}
+
+func TestApp_Analyze_clean(t *testing.T) {
+	a, err := New("testdata/23456789_feb.html", "98765432", "OOO STAR", "", 4000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, anomaly := range a.Analyze(DefaultLimits()) {
+		t.Errorf("Analyze() unexpected %v", anomaly)
+	}
+}
+
+func Test_runs(t *testing.T) {
+	rows := hourlyRows([]float64{0, 0, 1, 0, 0, 0})
+	rows[5].Date = rows[5].Date.Add(time.Hour) // gap before the last hour
+
+	got := runs(rows, func(i int) bool { return rows[i].PPlus == 0 })
+	want := [][2]int{{0, 1}, {3, 4}, {5, 5}}
+
+	if len(got) != len(want) {
+		t.Fatalf("runs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("runs() = %v, want %v", got, want)
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package app
 
 import (
+	"crypto/sha256"
 	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -19,37 +24,242 @@ type App struct {
 	Month       int
 	Year        int
 	Total       float64
+	Conflicts   []*Conflict
+	Schedule    *Schedule
+	Zones       []*ZoneTotal
+	Calendar    *Calendar
+	PeakHours   PeakHours
+	Capacity    float64 // purchased capacity in MW
+	CapacityErr string  // why the capacity can't be calculated from PeakHours
+	Scaled      bool    // the rows are read from the 80020 files, the coefficients aren't applied
+	Correction  float64 // factor of the month hours matching the register delta, 0 is none
+
+	Reconciliation *Reconciliation      // the profile total against the register readings
+	Coefficients   []*CoefficientPeriod // the coefficient history, Coefficient is used outside of it
+	Sources        []*Source            // the files of the meter exports
+	Export         *Export              // the spreadsheets written next to the 80020 files
+	INN            string               // INN of the sender of the 80020 files
+	Recipient      *Recipient           // the energy company receiving the 80020 files
+}
+
+// Mapping contract and coefficient of a single meter
+type Mapping struct {
+	Contract    string
+	Coefficient float64
 }
 
 // New return App
 func New(filename, contract, companyName, meter string, coefficient float64) (*App, error) {
+	profiles, err := readProfiles(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := selectProfile(profiles, meter)
+	if err != nil {
+		return nil, err
+	}
+
+	return newApp(p, contract, companyName, meter, coefficient), nil
+}
+
+// NewMerged return App of a single meter whose rows are merged from several exports,
+// conflicting rows of the same hour are kept in Conflicts
+func NewMerged(filenames []string, contract, companyName, meter string, coefficient float64) (*App, error) {
+	if len(filenames) == 0 {
+		return nil, errors.New("there should be at least one file")
+	}
+
+	var selected []*Profile
+
+	for _, filename := range filenames {
+		profiles, err := readProfiles(filename)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+
+		p, err := selectProfile(profiles, meter)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+
+		selected = append(selected, p)
+	}
+
+	profile, conflicts, err := merge(selected)
+	if err != nil {
+		return nil, err
+	}
+
+	a := newApp(profile, contract, companyName, meter, coefficient)
+	a.Conflicts = conflicts
+
+	return a, nil
+}
+
+// selectProfile return the table of the meter, the only table of the export or the first one
+// if meter is empty
+func selectProfile(profiles []*Profile, meter string) (*Profile, error) {
+	for _, p := range profiles {
+		if p.Meter == meter {
+			return p, nil
+		}
+	}
+
+	if len(profiles) > 1 && meter != "" {
+		return nil, fmt.Errorf("meter %s not found", meter)
+	}
+
+	return profiles[0], nil
+}
+
+// NewAll return App for each meter table of the file,
+// contract and coefficient are taken from mappings by the meter serial number,
+// def is used for meters without mapping
+func NewAll(filename, companyName string, def Mapping, mappings map[string]Mapping) ([]*App, error) {
+	profiles, err := readProfiles(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return newApps(profiles, companyName, def, mappings), nil
+}
+
+// newApps return App for each meter table with the contract and coefficient of its mapping
+func newApps(profiles []*Profile, companyName string, def Mapping, mappings map[string]Mapping) []*App {
+	var apps []*App
+
+	for _, p := range profiles {
+		m, ok := mappings[p.Meter]
+		if !ok {
+			m = def
+		}
+
+		apps = append(apps, newApp(p, m.Contract, companyName, "", m.Coefficient))
+	}
+
+	return apps
+}
+
+// NewXML return App for each accountpoint of the 80020 files whose values are already scaled,
+// contract is taken from mappings by the accountpoint code, def is used for the others, the coefficients are ignored,
+// the meter is the code without the contract and the sender is used if companyName is empty
+func NewXML(filenames []string, companyName string, def Mapping, mappings map[string]Mapping) ([]*App, error) {
+	profiles, sender, err := ReadXML(filenames)
+	if err != nil {
+		return nil, err
+	}
+
+	return newXMLApps(profiles, sender, companyName, def, mappings), nil
+}
+
+// NewZip return App for each accountpoint of the 80020 files of the *.zip archive as NewXML does
+func NewZip(filename, companyName string, def Mapping, mappings map[string]Mapping) ([]*App, error) {
+	profiles, sender, err := ReadZip(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return newXMLApps(profiles, sender, companyName, def, mappings), nil
+}
+
+// newXMLApps return App for each accountpoint profile of the 80020 files
+func newXMLApps(profiles []*Profile, sender, companyName string, def Mapping, mappings map[string]Mapping) []*App {
+	if companyName == "" {
+		companyName = sender
+	}
+
+	var apps []*App
+
+	for _, p := range profiles {
+		m, ok := mappings[p.Meter]
+		if !ok {
+			m = def
+		}
+
+		meter := strings.TrimPrefix(p.Meter, m.Contract)
+		if meter == "" {
+			meter = p.Meter
+		}
+
+		a := newApp(p, m.Contract, companyName, meter, 1)
+		a.Scaled = true
+		apps = append(apps, a)
+	}
+
+	return apps
+}
+
+// NewAllFrom return App for each meter table of the export read from r as NewAll does,
+// name is the source name of the summary
+func NewAllFrom(r io.Reader, name, companyName string, def Mapping, mappings map[string]Mapping) ([]*App, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := parseProfiles(data, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return newApps(profiles, companyName, def, mappings), nil
+}
+
+// readProfiles return meter tables with rows of the file
+func readProfiles(filename string) ([]*Profile, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, dataMeter, err := parse(data)
+	return parseProfiles(data, filepath.Base(filename))
+}
+
+// parseProfiles return meter tables with rows of the export data, name is the source name
+func parseProfiles(data []byte, name string) ([]*Profile, error) {
+	profiles, err := parse(data)
 	if err != nil {
 		return nil, err
 	}
 
-	if meter == "" && dataMeter != "" {
-		meter = dataMeter
+	source := &Source{Filename: name, SHA256: fmt.Sprintf("%x", sha256.Sum256(data))}
+
+	var filled []*Profile
+	for _, p := range profiles {
+		if len(p.Rows) != 0 {
+			p.Sources = []*Source{source}
+			filled = append(filled, p)
+		}
+	}
+
+	if len(filled) == 0 {
+		return nil, errors.New("bad data: no rows found")
+	}
+
+	return filled, nil
+}
+
+// newApp return App for a single meter table
+func newApp(p *Profile, contract, companyName, meter string, coefficient float64) *App {
+	if meter == "" && p.Meter != "" {
+		meter = p.Meter
 	}
 
-	firstRow := rows[0]
+	firstRow := p.Rows[0]
 
 	return &App{
 		Contract:    contract,
 		CompanyName: companyName,
 		Meter:       meter,
 		Coefficient: coefficient,
-		Rows:        rows,
+		Rows:        p.Rows,
 		FirstDay:    firstRow.Date.Day(),
 		FirstHour:   firstRow.Date.Hour(),
 		Month:       int(firstRow.Date.Month()),
 		Year:        firstRow.Date.Year(),
-		DaysInMonth: daysInMonth(firstRow.Date)}, nil
+		DaysInMonth: daysInMonth(firstRow.Date),
+		Sources:     p.Sources}
 }
 
 // daysInMonth return days in month at a selected period
@@ -59,53 +269,185 @@ func daysInMonth(t time.Time) int {
 
 // Run application start
 func (a *App) Run() error {
-	if a.DaysInMonth == 0 {
-		return errors.New("there should be no 0 days in a month")
+	return RunAll([]*App{a})
+}
+
+// RunAll writes one file per day and contract with an accountpoint for each App and the summary files,
+// the sender is taken from the first App of the contract
+func RunAll(apps []*App) error {
+	_, err := RunAllIn(".", apps)
+
+	return err
+}
+
+// RunAllIn writes the files as RunAll does into the month directory under root and return the directory
+func RunAllIn(root string, apps []*App) (string, error) {
+	days, err := prepare(apps)
+	if err != nil {
+		return "", err
+	}
+
+	contracts, err := byContract(apps)
+	if err != nil {
+		return "", err
 	}
 
-	dirName, err := createDir(a.Month, a.Year)
+	dirName, err := createDir(root, apps[0].Month, apps[0].Year)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	index := 0
+	var files []string
 
-	for i := 1; i <= a.DaysInMonth; i++ {
-		daily := make([]float64, 24)
+	for _, indexes := range contracts {
+		first := apps[indexes[0]]
 
-		if i >= a.FirstDay {
-			for j := 0; j < len(daily); j++ {
-				if j+1 >= a.FirstHour {
-					if index < len(a.Rows) {
-						p := a.Rows[index].PPlus * a.Coefficient
-						a.Total += p
+		for i := 1; i <= first.DaysInMonth; i++ {
+			date := time.Date(first.Year, time.Month(first.Month), i, 0, 0, 0, 0, time.UTC)
+			head := newHead(date, first.Contract, first.CompanyName, first.Meter)
+			head.INN = first.INN
+			head.AreaINN, head.AreaName = first.area()
 
-						index++
+			var points []*Point
 
-						daily[j] = p
-					}
+			for _, j := range indexes {
+				body, err := newBody(days[j][i-1])
+				if err != nil {
+					return "", err
 				}
+
+				points = append(points, &Point{Contract: apps[j].Contract, Meter: apps[j].Meter, Body: body})
 			}
-		}
 
-		date := time.Date(a.Year, time.Month(a.Month), i, 0, 0, 0, 0, time.UTC)
-		head := newHead(date, a.Contract, a.CompanyName, a.Meter)
+			buff, err := toBufferPoints(head, points)
+			if err != nil {
+				return "", err
+			}
 
-		body, err := newBody(daily)
-		if err != nil {
-			return err
+			err = toFile(buff, dirName, head)
+			if err != nil {
+				return "", err
+			}
+			files = append(files, fileName(head))
 		}
+	}
 
-		buff, err := toBuffer(head, body)
+	for _, a := range apps {
+		exported, err := a.export(dirName)
 		if err != nil {
-			return err
+			return "", fmt.Errorf("meter %s: %w", a.Meter, err)
 		}
+		files = append(files, exported...)
+	}
 
-		err = toFile(buff, dirName, head)
-		if err != nil {
-			return err
+	return dirName, writeReport(dirName, newReport(apps, days, files))
+}
+
+// byContract return the indexes of the apps grouped by the contract in the order of the apps,
+// the meters of a contract share its files and should have the same sender and area
+func byContract(apps []*App) ([][]int, error) {
+	var contracts [][]int
+	index := make(map[string]int)
+
+	for i, a := range apps {
+		c, ok := index[a.Contract]
+		if !ok {
+			index[a.Contract] = len(contracts)
+			contracts = append(contracts, []int{i})
+			continue
+		}
+
+		first := apps[contracts[c][0]]
+		if a.CompanyName != first.CompanyName || a.INN != first.INN {
+			return nil, fmt.Errorf("meter %s: the meters of contract %s should have the same company", a.Meter, a.Contract)
+		}
+		areaINN, areaName := a.area()
+		firstINN, firstName := first.area()
+		if areaINN != firstINN || areaName != firstName {
+			return nil, fmt.Errorf("meter %s: the meters of contract %s should have the same area", a.Meter, a.Contract)
+		}
+
+		contracts[c] = append(contracts[c], i)
+	}
+
+	return contracts, nil
+}
+
+// area return the INN and the name of the area of the recipient, empty without the recipient
+func (a *App) area() (string, string) {
+	if a.Recipient == nil {
+		return "", ""
+	}
+
+	return a.Recipient.AreaINN, a.Recipient.AreaName
+}
+
+// prepare checks that the apps share the month and return their daily values,
+// Total, Zones, Capacity and CapacityErr of each App are set
+func prepare(apps []*App) ([][][]float64, error) {
+	if len(apps) == 0 {
+		return nil, errors.New("there should be at least one meter")
+	}
+
+	first := apps[0]
+
+	for _, a := range apps {
+		if a.DaysInMonth == 0 {
+			return nil, errors.New("there should be no 0 days in a month")
+		}
+		if a.Month != first.Month || a.Year != first.Year {
+			return nil, fmt.Errorf("meter %s: all meters should have the same month", a.Meter)
 		}
 	}
 
-	return nil
+	days := make([][][]float64, len(apps))
+	for i, a := range apps {
+		days[i] = a.daily()
+
+		a.Capacity = 0
+		a.CapacityErr = ""
+		if a.PeakHours != nil {
+			// the capacity doesn't prevent the conversion, e.g. without consumption in the peak hours
+			capacity, err := a.peakCapacity(a.PeakHours)
+			if err != nil {
+				a.CapacityErr = err.Error()
+			}
+			a.Capacity = capacity
+		}
+	}
+
+	return days, nil
+}
+
+// daily return the scaled hourly values for each day of the month and sets Total,
+// a row dated hh:00 is the value of the hour ending at hh
+func (a *App) daily() [][]float64 {
+	days := make([][]float64, a.DaysInMonth)
+	for i := range days {
+		days[i] = make([]float64, 24)
+	}
+
+	a.Total = 0
+	a.Zones = nil
+	if a.Schedule != nil {
+		a.Zones = a.Schedule.newZoneTotals(time.Month(a.Month))
+	}
+
+	for _, r := range a.Rows {
+		start := r.Date.Add(-time.Hour)
+		if start.Year() != a.Year || int(start.Month()) != a.Month || start.Day() > a.DaysInMonth {
+			continue
+		}
+
+		p := r.PPlus * a.coefficient(r)
+		a.Total += p
+
+		if a.Schedule != nil {
+			a.Zones = addZone(a.Zones, a.Schedule.zone(start), p)
+		}
+
+		days[start.Day()-1][start.Hour()] = p
+	}
+
+	return days
 }
@@ -1,9 +1,12 @@
 package app
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -72,6 +75,7 @@ func TestNew(t *testing.T) {
 				Month:       2,
 				Year:        2020,
 				Total:       0,
+				Sources:     []*Source{{Filename: "first_row.html", SHA256: "f765339f5fb181fad9ea5acabbb1e2711a1d2ac9cc71cf3d336e6248c1be3262"}},
 			},
 			wantErr: false,
 		},
@@ -171,3 +175,157 @@ func TestApp_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestNewAll(t *testing.T) {
+	mappings := map[string]Mapping{
+		"12345678": {Contract: "11111111", Coefficient: 2},
+	}
+
+	got, err := NewAll("testdata/two_meters.html", "OOO STAR", Mapping{Contract: "98765432", Coefficient: 4000}, mappings)
+	if err != nil {
+		t.Fatalf("NewAll() error = %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("NewAll() len = %d, want 2", len(got))
+	}
+
+	tests := []struct {
+		meter       string
+		contract    string
+		coefficient float64
+		pPlus       float64
+	}{
+		{meter: "23456789", contract: "98765432", coefficient: 4000, pPlus: 0.0705},
+		{meter: "12345678", contract: "11111111", coefficient: 2, pPlus: 0.1410},
+	}
+	for i, tt := range tests {
+		t.Run(tt.meter, func(t *testing.T) {
+			a := got[i]
+			if a.Meter != tt.meter || a.Contract != tt.contract || a.Coefficient != tt.coefficient {
+				t.Errorf("NewAll() = %s %s %v, want %s %s %v", a.Meter, a.Contract, a.Coefficient, tt.meter, tt.contract, tt.coefficient)
+			}
+			if len(a.Rows) != 1 || a.Rows[0].PPlus != tt.pPlus {
+				t.Errorf("NewAll() rows of %s are mixed with another meter", tt.meter)
+			}
+		})
+	}
+}
+
+func TestNew_selectMeter(t *testing.T) {
+	a, err := New("testdata/two_meters.html", "98765432", "OOO STAR", "12345678", 1)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if len(a.Rows) != 1 || a.Rows[0].PPlus != 0.1410 {
+		t.Errorf("New() rows of another meter")
+	}
+
+	// the meter isn't relabeled when the export has several tables
+	if _, err := New("testdata/two_meters.html", "98765432", "OOO STAR", "99999999", 1); err == nil {
+		t.Errorf("New() of a missing meter should fail")
+	}
+	if _, err := NewMerged([]string{"testdata/two_meters.html"}, "98765432", "OOO STAR", "99999999", 1); err == nil {
+		t.Errorf("NewMerged() of a missing meter should fail")
+	}
+}
+
+func TestRunAll(t *testing.T) {
+	apps, err := NewAll("testdata/two_meters.html", "OOO STAR", Mapping{Contract: "98765432", Coefficient: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RunAll(apps); err != nil {
+		t.Fatalf("RunAll() error = %v", err)
+	}
+
+	dirName := "80020-02-2020"
+	defer os.RemoveAll(dirName)
+
+	data, err := os.ReadFile(path.Join(dirName, "80020_001_98765432_01022020.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, code := range []string{"9876543223456789", "9876543212345678"} {
+		if !bytes.Contains(data, []byte(`<accountpoint code="`+code+`"`)) {
+			t.Errorf("RunAll() file don't contain accountpoint %s", code)
+		}
+	}
+
+	if err := RunAll(nil); err == nil {
+		t.Errorf("RunAll() without meters should fail")
+	}
+}
+
+func TestRunAllIn_contracts(t *testing.T) {
+	mappings := map[string]Mapping{
+		"12345678": {Contract: "11111111", Coefficient: 1},
+	}
+
+	apps, err := NewAll("testdata/two_meters.html", "OOO STAR", Mapping{Contract: "98765432", Coefficient: 1}, mappings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apps[1].Recipient = &Recipient{AreaINN: "7701234567", AreaName: "AREA"}
+
+	dirName, err := RunAllIn(t.TempDir(), apps)
+	if err != nil {
+		t.Fatalf("RunAllIn() error = %v", err)
+	}
+
+	tests := []struct {
+		file    string
+		code    string
+		area    string
+		without string
+	}{
+		{file: "80020_001_98765432_01022020.xml", code: "9876543223456789", area: "<inn>0000000000</inn>", without: "1111111112345678"},
+		{file: "80020_001_11111111_01022020.xml", code: "1111111112345678", area: "<inn>7701234567</inn>", without: "9876543223456789"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(path.Join(dirName, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Contains(data, []byte(`<accountpoint code="`+tt.code+`"`)) || bytes.Contains(data, []byte(tt.without)) {
+				t.Errorf("RunAllIn() file %s should contain only accountpoint %s", tt.file, tt.code)
+			}
+			if !bytes.Contains(data, []byte(tt.area)) {
+				t.Errorf("RunAllIn() file %s should have area %s", tt.file, tt.area)
+			}
+		})
+	}
+
+	// the meters of one contract share the sender and the area of its files
+	apps[1].Contract = "98765432"
+	if _, err := RunAllIn(t.TempDir(), apps); err == nil || !strings.Contains(err.Error(), "the same area") {
+		t.Errorf("RunAllIn() error = %v, want the meters of different areas refused", err)
+	}
+	apps[1].Recipient = nil
+	apps[1].CompanyName = "OOO MOON"
+	if _, err := RunAllIn(t.TempDir(), apps); err == nil || !strings.Contains(err.Error(), "the same company") {
+		t.Errorf("RunAllIn() error = %v, want the meters of different companies refused", err)
+	}
+}
+
+func TestNewAllFrom(t *testing.T) {
+	data, err := os.ReadFile("testdata/two_meters.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewAllFrom(bytes.NewReader(data), "stdin", "OOO STAR", Mapping{Contract: "98765432", Coefficient: 1}, nil)
+	if err != nil {
+		t.Fatalf("NewAllFrom() error = %v", err)
+	}
+	if len(got) != 2 || got[1].Meter != "12345678" || got[0].Sources[0].Filename != "stdin" {
+		t.Errorf("NewAllFrom() = %v", got)
+	}
+
+	if _, err := NewAllFrom(bytes.NewReader(nil), "stdin", "", Mapping{}, nil); err == nil {
+		t.Errorf("NewAllFrom() of empty input should fail")
+	}
+}
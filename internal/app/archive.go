@@ -0,0 +1,102 @@
+package app
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Archive formats of the month files
+const (
+	ArchiveZip = "zip"
+	ArchiveTar = "tar"
+)
+
+// WriteArchive writes the files of the month directory as a zip or tar archive,
+// the names in the archive start with the directory name
+func WriteArchive(w io.Writer, dirName, format string) error {
+	if format != ArchiveZip && format != ArchiveTar {
+		return fmt.Errorf("bad archive format %s: zip or tar required", format)
+	}
+
+	entries, err := os.ReadDir(dirName)
+	if err != nil {
+		return err
+	}
+
+	prefix := filepath.Base(dirName)
+
+	switch format {
+	case ArchiveZip:
+		zw := zip.NewWriter(w)
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			err = addFile(dirName, e, func(info os.FileInfo, f *os.File) error {
+				header, err := zip.FileInfoHeader(info)
+				if err != nil {
+					return err
+				}
+				header.Name = path.Join(prefix, e.Name())
+				header.Method = zip.Deflate
+
+				fw, err := zw.CreateHeader(header)
+				if err != nil {
+					return err
+				}
+				_, err = io.Copy(fw, f)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	default:
+		tw := tar.NewWriter(w)
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			err = addFile(dirName, e, func(info os.FileInfo, f *os.File) error {
+				header, err := tar.FileInfoHeader(info, "")
+				if err != nil {
+					return err
+				}
+				header.Name = path.Join(prefix, e.Name())
+
+				err = tw.WriteHeader(header)
+				if err != nil {
+					return err
+				}
+				_, err = io.Copy(tw, f)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return tw.Close()
+	}
+}
+
+// addFile opens the file of the directory entry and adds it by add
+func addFile(dirName string, e os.DirEntry, add func(info os.FileInfo, f *os.File) error) error {
+	info, err := e.Info()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(filepath.Join(dirName, e.Name()))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return add(info, f)
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestWriteArchive(t *testing.T) {
+	apps, err := NewAll("testdata/first_row.html", "OOO STAR", Mapping{Contract: "98765432", Coefficient: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dirName, err := RunAllIn(t.TempDir(), apps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 29 day files and the summary files
+	want := 29 + len(summaryFiles)
+	first := "80020-02-2020/80020_001_98765432_01022020.xml"
+
+	var b bytes.Buffer
+	if err := WriteArchive(&b, dirName, ArchiveZip); err != nil {
+		t.Fatalf("WriteArchive() zip error = %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != want || zr.File[0].Name != first {
+		t.Errorf("WriteArchive() zip has %d files starting with %s", len(zr.File), zr.File[0].Name)
+	}
+
+	b.Reset()
+	if err := WriteArchive(&b, dirName, ArchiveTar); err != nil {
+		t.Fatalf("WriteArchive() tar error = %v", err)
+	}
+	tr := tar.NewReader(&b)
+	var names []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, h.Name)
+	}
+	if len(names) != want || names[0] != first {
+		t.Errorf("WriteArchive() tar = %v", names)
+	}
+
+	if err := WriteArchive(&b, dirName, "rar"); err == nil {
+		t.Errorf("WriteArchive() of a bad format should fail")
+	}
+}
@@ -0,0 +1,181 @@
+package app
+
+import (
+	"embed"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed calendar
+var calendarFS embed.FS
+
+var (
+	defaultCalendar     *Calendar
+	defaultCalendarErr  error
+	defaultCalendarOnce sync.Once
+)
+
+// Types of calendar days
+const (
+	dayOff     = 1 // a holiday or a moved day off
+	dayShort   = 2 // a shortened working day
+	dayWorking = 3 // a working day moved to the weekend
+)
+
+// Calendar production calendar, days not listed follow the weekday rule
+// and the years without data know weekends only
+type Calendar struct {
+	days map[string]int // by date 2006-01-02
+}
+
+// xmlCalendar the production calendar file of xmlcalendar.ru
+type xmlCalendar struct {
+	Year int `xml:"year,attr"`
+	Days []struct {
+		D string `xml:"d,attr"` // 01.02 is the second of January
+		T int    `xml:"t,attr"`
+	} `xml:"days>day"`
+}
+
+// DefaultCalendar return the bundled production calendar of Russia
+func DefaultCalendar() (*Calendar, error) {
+	defaultCalendarOnce.Do(func() {
+		defaultCalendar, defaultCalendarErr = bundledCalendar()
+	})
+
+	return defaultCalendar, defaultCalendarErr
+}
+
+// bundledCalendar reads the calendar files embedded into the binary
+func bundledCalendar() (*Calendar, error) {
+	c := &Calendar{days: make(map[string]int)}
+
+	entries, err := calendarFS.ReadDir("calendar")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		data, err := calendarFS.ReadFile(path.Join("calendar", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.add(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+	}
+
+	return c, nil
+}
+
+// LoadCalendar return the bundled production calendar,
+// the years of the *.xml files replace the bundled ones
+func LoadCalendar(filenames ...string) (*Calendar, error) {
+	c, err := bundledCalendar()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.add(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+
+	return c, nil
+}
+
+// add adds the days of the single year calendar
+func (c *Calendar) add(data []byte) error {
+	x := &xmlCalendar{}
+
+	err := xml.Unmarshal(data, x)
+	if err != nil {
+		return err
+	}
+
+	if x.Year == 0 {
+		return errors.New("bad calendar: year required")
+	}
+
+	prefix := fmt.Sprintf("%d-", x.Year)
+	for date := range c.days {
+		if strings.HasPrefix(date, prefix) {
+			delete(c.days, date)
+		}
+	}
+
+	for _, d := range x.Days {
+		date, err := time.Parse("2006.01.02", fmt.Sprintf("%d.%s", x.Year, d.D))
+		if err != nil {
+			return err
+		}
+		if d.T < dayOff || d.T > dayWorking {
+			return fmt.Errorf("bad calendar: day %s has type %d", d.D, d.T)
+		}
+
+		c.days[date.Format("2006-01-02")] = d.T
+	}
+
+	return nil
+}
+
+// IsWorkingDay return true if t is a working day, nil calendar is the bundled one
+func (c *Calendar) IsWorkingDay(t time.Time) bool {
+	switch c.dayType(t) {
+	case dayOff:
+		return false
+	case dayShort, dayWorking:
+		return true
+	}
+
+	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+}
+
+// IsShortDay return true if t is a shortened working day
+func (c *Calendar) IsShortDay(t time.Time) bool {
+	return c.dayType(t) == dayShort
+}
+
+// dayType return the type of the listed day, 0 for the day following the weekday rule
+func (c *Calendar) dayType(t time.Time) int {
+	if c == nil {
+		var err error
+		c, err = DefaultCalendar()
+		if err != nil {
+			return 0
+		}
+	}
+
+	return c.days[t.Format("2006-01-02")]
+}
+
+// profileDay return the weekday whose profile the day follows:
+// days off are Sundays and working weekends are Mondays
+func (c *Calendar) profileDay(t time.Time) time.Weekday {
+	working := c.IsWorkingDay(t)
+	weekend := t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+
+	switch {
+	case !working && !weekend:
+		return time.Sunday
+	case working && weekend:
+		return time.Monday
+	}
+
+	return t.Weekday()
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalendar_IsWorkingDay(t *testing.T) {
+	cal, err := LoadCalendar("testdata/calendar_2020.xml")
+	if err != nil {
+		t.Fatalf("LoadCalendar() error = %v", err)
+	}
+
+	plain, err := LoadCalendar("testdata/calendar_2020_plain.xml")
+	if err != nil {
+		t.Fatalf("LoadCalendar() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cal  *Calendar
+		date time.Time
+		want bool
+	}{
+		{name: "monday", cal: cal, date: time.Date(2020, 2, 3, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "saturday", cal: cal, date: time.Date(2020, 2, 22, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "moved holiday", cal: cal, date: time.Date(2020, 2, 24, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "shortened day", cal: cal, date: time.Date(2020, 4, 30, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "new year holidays", cal: cal, date: time.Date(2020, 1, 8, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "user file replaces the year", cal: plain, date: time.Date(2020, 2, 24, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "user file keeps other years", cal: plain, date: time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "2021 moved from saturday 20 february", cal: nil, date: time.Date(2021, 2, 22, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "2021 working saturday", cal: nil, date: time.Date(2021, 2, 20, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "2022 working saturday", cal: nil, date: time.Date(2022, 3, 5, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "2022 moved from saturday 5 march", cal: nil, date: time.Date(2022, 3, 7, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "2023 moved from sunday 1 january", cal: nil, date: time.Date(2023, 2, 24, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "2023 moved from saturday 4 november", cal: nil, date: time.Date(2023, 11, 6, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "2024 working saturday", cal: nil, date: time.Date(2024, 4, 27, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "2024 moved from saturday 2 november", cal: nil, date: time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "2024 working saturday before new year", cal: nil, date: time.Date(2024, 12, 28, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "2025 moved from saturday 4 january", cal: nil, date: time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "2025 working saturday", cal: nil, date: time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "2026 moved from saturday 3 january", cal: nil, date: time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "year without data", cal: nil, date: time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cal.IsWorkingDay(tt.date); got != tt.want {
+				t.Errorf("IsWorkingDay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultCalendar(t *testing.T) {
+	cal, err := DefaultCalendar()
+	if err != nil {
+		t.Fatalf("DefaultCalendar() error = %v", err)
+	}
+
+	// the number of working days of the official production calendar
+	tests := map[int]int{2020: 248, 2021: 247, 2022: 247, 2023: 247, 2024: 248, 2025: 247, 2026: 247}
+
+	for year, want := range tests {
+		var got int
+		for d := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC); d.Year() == year; d = d.AddDate(0, 0, 1) {
+			if cal.IsWorkingDay(d) {
+				got++
+			}
+		}
+		if got != want {
+			t.Errorf("DefaultCalendar() %d working days = %d, want %d", year, got, want)
+		}
+	}
+
+	if !cal.IsShortDay(time.Date(2024, 2, 22, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("IsShortDay() 22.02.2024 should be shortened")
+	}
+}
+
+func TestCalendar_profileDay(t *testing.T) {
+	tests := []struct {
+		name string
+		date time.Time
+		want time.Weekday
+	}{
+		{name: "working day", date: time.Date(2024, 4, 26, 0, 0, 0, 0, time.UTC), want: time.Friday},
+		{name: "holiday", date: time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC), want: time.Sunday},
+		{name: "working saturday", date: time.Date(2024, 4, 27, 0, 0, 0, 0, time.UTC), want: time.Monday},
+		{name: "sunday", date: time.Date(2024, 4, 28, 0, 0, 0, 0, time.UTC), want: time.Sunday},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cal *Calendar
+			if got := cal.profileDay(tt.date); got != tt.want {
+				t.Errorf("profileDay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCalendar(t *testing.T) {
+	if _, err := LoadCalendar("testdata/empty.html"); err == nil {
+		t.Errorf("LoadCalendar() of an empty file should fail")
+	}
+	if _, err := LoadCalendar("testdata/not_exist.xml"); err == nil {
+		t.Errorf("LoadCalendar() of a missing file should fail")
+	}
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PeakHours the system operator's planned peak hour start by date 02.01.2006
+type PeakHours map[string]int
+
+// LoadPeakHours reads the planned peak hours from the *.csv file with rows "date;hour",
+// the hour is the start of the peak hour from 0 to 23
+func LoadPeakHours(filename string) (PeakHours, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	peaks := make(PeakHours)
+
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		row := strings.TrimSpace(string(line))
+		if row == "" || strings.HasPrefix(row, "date") { // skips the header
+			continue
+		}
+
+		fields := strings.Split(row, ";")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: date;hour required", filename, i+1)
+		}
+
+		_, err := time.Parse("02.01.2006", fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, i+1, err)
+		}
+
+		hour, err := strconv.Atoi(fields[1])
+		if err != nil || hour < 0 || hour > 23 {
+			return nil, fmt.Errorf("%s:%d: bad hour %q", filename, i+1, fields[1])
+		}
+
+		peaks[fields[0]] = hour
+	}
+
+	if len(peaks) == 0 {
+		return nil, fmt.Errorf("%s: no peak hours found", filename)
+	}
+
+	return peaks, nil
+}
+
+// peakCapacity return the purchased capacity in MW, the average consumption during
+// the planned peak hours of the working days
+func (a *App) peakCapacity(peaks PeakHours) (float64, error) {
+	if len(peaks) == 0 {
+		return 0, errors.New("planned peak hours required")
+	}
+
+	hours := make(map[time.Time]float64)
+	for _, h := range a.hours() {
+		hours[h.Start] = h.Value
+	}
+
+	var dates []string
+	for date := range peaks {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	var sum float64
+	var days int
+
+	for _, date := range dates {
+		day, err := time.Parse("02.01.2006", date)
+		if err != nil {
+			return 0, err
+		}
+
+		if !a.Calendar.IsWorkingDay(day) {
+			continue
+		}
+
+		v, ok := hours[day.Add(time.Duration(peaks[date])*time.Hour)]
+		if !ok {
+			continue
+		}
+
+		sum += v
+		days++
+	}
+
+	if days == 0 {
+		return 0, errors.New("no consumption during the planned peak hours")
+	}
+
+	// the hourly kWh is the average power of the hour in kW
+	return sum / float64(days) / 1000, nil
+}
+
+// isPeakDay return true if the day of the hour starting at t is a working day of the planned peak hours
+func (a *App) isPeakDay(peaks PeakHours, t time.Time) bool {
+	if _, ok := peaks[t.Format("02.01.2006")]; !ok {
+		return false
+	}
+
+	return a.Calendar.IsWorkingDay(t)
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPeakHours(t *testing.T) {
+	got, err := LoadPeakHours("testdata/peak_hours_feb.csv")
+	if err != nil {
+		t.Fatalf("LoadPeakHours() error = %v", err)
+	}
+
+	if len(got) != 20 {
+		t.Errorf("LoadPeakHours() len = %d, want 20", len(got))
+	}
+	if got["03.02.2020"] != 10 || got["04.02.2020"] != 18 {
+		t.Errorf("LoadPeakHours() = %v", got)
+	}
+
+	if _, err := LoadPeakHours("testdata/first_row.html"); err == nil {
+		t.Errorf("LoadPeakHours() of a bad file should fail")
+	}
+}
+
+func TestApp_peakCapacity(t *testing.T) {
+	// 03.02.2020 monday and 24.02.2020 the moved holiday
+	rows := hourlyRows(make([]float64, 24*24))
+	for _, r := range rows {
+		r.PPlus = 1
+	}
+	rows[24*2+10].PPlus = 3
+	rows[24*23+10].PPlus = 9
+
+	peaks := PeakHours{"03.02.2020": 10, "24.02.2020": 10}
+
+	plain, err := LoadCalendar("testdata/calendar_2020_plain.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		calendar *Calendar
+		want     float64
+	}{
+		{name: "calendar without holidays", calendar: plain, want: (3 + 9) / 2.0},
+		{name: "bundled calendar skips holiday", calendar: nil, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &App{Coefficient: 1000, Rows: rows, Month: 2, Year: 2020, Calendar: tt.calendar}
+
+			got, err := a.peakCapacity(peaks)
+			if err != nil {
+				t.Fatalf("peakCapacity() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("peakCapacity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	a := &App{Coefficient: 1, Rows: rows, Month: 2, Year: 2020}
+	if _, err := a.peakCapacity(PeakHours{"03.03.2020": 10}); err == nil {
+		t.Errorf("peakCapacity() without consumption in the peak hours should fail")
+	}
+}
+
+func TestRunAllIn_capacityError(t *testing.T) {
+	apps, err := NewAll("testdata/first_row.html", "OOO STAR", Mapping{Contract: "98765432", Coefficient: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the peak hours out of the month don't prevent the conversion
+	apps[0].PeakHours = PeakHours{"03.03.2020": 10}
+
+	dirName, err := RunAllIn(t.TempDir(), apps)
+	if err != nil {
+		t.Fatalf("RunAllIn() error = %v", err)
+	}
+	if apps[0].CapacityErr == "" || apps[0].Capacity != 0 {
+		t.Errorf("RunAllIn() capacity = %v, error %q", apps[0].Capacity, apps[0].CapacityErr)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dirName, "summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"capacity_error"`) {
+		t.Errorf("summary.json without the capacity error")
+	}
+}
@@ -0,0 +1,229 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Chart the scaled hourly P+ of the month by days and hours for the terminal
+type Chart struct {
+	Days     []time.Time
+	Values   [][]float64 // by day and hour, NaN is a missing hour
+	PeakDay  int         // the day index of the highest hour, -1 without values
+	PeakHour int
+	Min      float64 // the lowest non-zero hour
+	Max      float64
+}
+
+// shades of the heatmap from zero to the highest hour
+var (
+	unicodeShades = []string{" ", "░", "▒", "▓", "█"}
+	asciiShades   = []string{" ", ".", ":", "*", "#"}
+)
+
+// Chart return the chart of the month, shift hours are added to the meter time
+func (a *App) Chart(shift int) *Chart {
+	c := &Chart{PeakDay: -1}
+
+	from := time.Date(a.Year, time.Month(a.Month), 1, 0, 0, 0, 0, time.UTC)
+	for d := 0; d < a.DaysInMonth; d++ {
+		c.Days = append(c.Days, from.AddDate(0, 0, d))
+
+		values := make([]float64, 24)
+		for h := range values {
+			values[h] = math.NaN()
+		}
+		c.Values = append(c.Values, values)
+	}
+
+	for _, r := range a.Rows {
+		start := r.Date.Add(time.Duration(shift-1) * time.Hour)
+		d := int(start.Sub(from) / (24 * time.Hour))
+		if start.Before(from) || d >= a.DaysInMonth {
+			continue
+		}
+
+		p := r.PPlus * a.coefficient(r)
+		c.Values[d][start.Hour()] = p
+
+		if c.PeakDay == -1 || p > c.Max {
+			c.PeakDay, c.PeakHour, c.Max = d, start.Hour(), p
+		}
+		if p > 0 && (c.Min == 0 || p < c.Min) {
+			c.Min = p
+		}
+	}
+
+	return c
+}
+
+// total return the sum of the day values
+func (c *Chart) total(d int) float64 {
+	var total float64
+	for _, v := range c.Values[d] {
+		if !math.IsNaN(v) {
+			total += v
+		}
+	}
+
+	return total
+}
+
+// WriteBars writes a bar of the total of each day, the longest bar is width characters
+func (c *Chart) WriteBars(w io.Writer, width int, ascii bool) error {
+	if width < 1 {
+		return fmt.Errorf("bad width %d", width)
+	}
+
+	var max float64
+	for d := range c.Days {
+		max = math.Max(max, c.total(d))
+	}
+
+	for d, day := range c.Days {
+		total := c.total(d)
+
+		var bar string
+		if max > 0 {
+			bar = bar8(total/max*float64(width), ascii)
+		}
+
+		_, err := fmt.Fprintf(w, "%s %s %-*s %.2f\n", day.Format("02.01"), day.Weekday().String()[:2], width, bar, total)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bar8 return a bar of n characters, the fractional part is drawn by eighth blocks
+func bar8(n float64, ascii bool) string {
+	if ascii {
+		return strings.Repeat("#", int(math.Round(n)))
+	}
+
+	eighths := []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+	full := int(n)
+
+	return strings.Repeat("█", full) + eighths[int((n-float64(full))*8)]
+}
+
+// WriteHeatmap writes a row of the shaded hours of each day from Min to Max, zero is blank,
+// a missing hour is ? in ASCII and · otherwise,
+// the highest hour is @ in ASCII and ◆ otherwise
+func (c *Chart) WriteHeatmap(w io.Writer, ascii bool) error {
+	shades, missing, peak := unicodeShades, "·", "◆"
+	if ascii {
+		shades, missing, peak = asciiShades, "?", "@"
+	}
+
+	// the hours start after the "02.01 Mo |" of the rows
+	_, err := fmt.Fprintf(w, "%10s%-6s%-6s%-6s%-6s\n", "", "0", "6", "12", "18")
+	if err != nil {
+		return err
+	}
+
+	for d, day := range c.Days {
+		var b strings.Builder
+		for h, v := range c.Values[d] {
+			switch {
+			case math.IsNaN(v):
+				b.WriteString(missing)
+			case d == c.PeakDay && h == c.PeakHour:
+				b.WriteString(peak)
+			case c.Max <= 0 || v <= 0:
+				b.WriteString(shades[0])
+			case c.Max == c.Min:
+				b.WriteString(shades[len(shades)-1])
+			default:
+				// any consumption is visible, the shades split the range between the lowest and the highest hour
+				i := 1 + int((v-c.Min)/(c.Max-c.Min)*float64(len(shades)-1))
+				if i >= len(shades) {
+					i = len(shades) - 1
+				}
+				b.WriteString(shades[i])
+			}
+		}
+
+		_, err = fmt.Fprintf(w, "%s %s |%s|\n", day.Format("02.01"), day.Weekday().String()[:2], b.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.PeakDay != -1 {
+		start := c.Days[c.PeakDay].Add(time.Duration(c.PeakHour) * time.Hour)
+		_, err = fmt.Fprintf(w, "peak %s %s %s - %s, %.2f kWh\n", peak, start.Format("02.01.2006"),
+			start.Format("15:04"), start.Add(time.Hour).Format("15:04"), c.Max)
+	}
+
+	return err
+}
+
+// WriteSVG writes the hourly values of the month as an SVG line chart of width × height pixels,
+// the line breaks at the missing hours and the highest hour is marked by a circle
+func (c *Chart) WriteSVG(w io.Writer, width, height int) error {
+	const left, right, top, bottom = 60, 10, 10, 24
+
+	plotW, plotH := float64(width-left-right), float64(height-top-bottom)
+	hours := len(c.Days) * 24
+
+	x := func(i int) float64 { return left + float64(i)*plotW/float64(hours) }
+	y := func(v float64) float64 {
+		if c.Max <= 0 {
+			return top + plotH
+		}
+		return top + plotH - v/c.Max*plotH
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-size="11" font-family="monospace">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="%.1f" fill="none" stroke="#ccc"/>`+"\n", left, top, plotW, plotH)
+
+	for d, day := range c.Days {
+		if d%7 != 0 {
+			continue
+		}
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.1f" stroke="#eee"/>`+"\n", x(d*24), top, x(d*24), top+plotH)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d">%s</text>`+"\n", x(d*24), height-8, day.Format("02.01"))
+	}
+
+	fmt.Fprintf(&b, `<text x="2" y="%d">%.2f</text>`+"\n", top+10, c.Max)
+	fmt.Fprintf(&b, `<text x="2" y="%.1f">0 kWh</text>`+"\n", top+plotH)
+
+	var points []string
+	line := func() {
+		if len(points) != 0 {
+			fmt.Fprintf(&b, `<polyline fill="none" stroke="#2a6ebb" stroke-width="1" points="%s"/>`+"\n", strings.Join(points, " "))
+		}
+		points = nil
+	}
+
+	for d := range c.Days {
+		for h, v := range c.Values[d] {
+			if math.IsNaN(v) {
+				line()
+				continue
+			}
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(d*24+h), y(v)))
+		}
+	}
+	line()
+
+	if c.PeakDay != -1 {
+		start := c.Days[c.PeakDay].Add(time.Duration(c.PeakHour) * time.Hour)
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="#c0392b"><title>peak %s, %.2f kWh</title></circle>`+"\n",
+			x(c.PeakDay*24+c.PeakHour), y(c.Max), start.Format("02.01.2006 15:04"), c.Max)
+	}
+
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestApp_Chart(t *testing.T) {
+	// the first day of February without its third hour, the peak is at 10:00
+	values := make([]float64, 24)
+	for i := range values {
+		values[i] = 1
+	}
+	values[10] = 4
+	rows := hourlyRows(values)
+	rows = append(rows[:2], rows[3:]...)
+
+	a := newApp(&Profile{Meter: "23456789", Rows: rows}, "98765432", "OOO STAR", "", 2)
+
+	tests := []struct {
+		name     string
+		shift    int
+		peakHour int
+		missing  int
+	}{
+		{name: "meter time", shift: 0, peakHour: 10, missing: 2},
+		{name: "shifted", shift: 3, peakHour: 13, missing: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := a.Chart(tt.shift)
+			if len(c.Days) != 29 || c.PeakDay != 0 || c.PeakHour != tt.peakHour || c.Max != 8 || c.Min != 2 {
+				t.Fatalf("Chart() peak %d %d %v of %d days", c.PeakDay, c.PeakHour, c.Max, len(c.Days))
+			}
+			if !math.IsNaN(c.Values[0][tt.missing]) || c.Values[0][tt.missing+1] != 2 {
+				t.Errorf("Chart() first day = %v", c.Values[0])
+			}
+		})
+	}
+
+	c := a.Chart(0)
+
+	var b strings.Builder
+	if err := c.WriteHeatmap(&b, true); err != nil {
+		t.Fatalf("WriteHeatmap() error = %v", err)
+	}
+	lines := strings.Split(b.String(), "\n")
+	if want := "01.02 Sa |..?.......@.............|"; lines[1] != want {
+		t.Errorf("WriteHeatmap() first day = %q, want %q", lines[1], want)
+	}
+	if i := strings.Index(lines[1], "|") + 1; strings.Index(lines[0], "0") != i || lines[0][i+6:i+7] != "6" {
+		t.Errorf("WriteHeatmap() hours %q over %q", lines[0], lines[1])
+	}
+	if !strings.Contains(b.String(), "peak @ 01.02.2020 10:00 - 11:00, 8.00 kWh") {
+		t.Errorf("WriteHeatmap() = %s", b.String())
+	}
+
+	b.Reset()
+	if err := c.WriteBars(&b, 10, false); err != nil {
+		t.Fatalf("WriteBars() error = %v", err)
+	}
+	if !strings.HasPrefix(b.String(), "01.02 Sa ██████████ 52.00\n02.02 Su            0.00\n") {
+		t.Errorf("WriteBars() = %s", b.String())
+	}
+	if err := c.WriteBars(&b, -3, false); err == nil {
+		t.Errorf("WriteBars() of a negative width should fail")
+	}
+}
+
+func TestChart_WriteSVG(t *testing.T) {
+	rows := hourlyRows([]float64{1, 2, 3, 4, 5})
+	rows = append(rows[:2], rows[3:]...)
+
+	a := newApp(&Profile{Meter: "23456789", Rows: rows}, "98765432", "OOO STAR", "", 1)
+
+	var b strings.Builder
+	if err := a.Chart(0).WriteSVG(&b, 800, 240); err != nil {
+		t.Fatalf("WriteSVG() error = %v", err)
+	}
+
+	svg := b.String()
+	if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>\n") {
+		t.Errorf("WriteSVG() = %s", svg)
+	}
+	// the missing third hour splits the line
+	if n := strings.Count(svg, "<polyline"); n != 2 {
+		t.Errorf("WriteSVG() has %d lines, want 2", n)
+	}
+	if !strings.Contains(svg, "<title>peak 01.02.2020 04:00, 5.00 kWh</title>") {
+		t.Errorf("WriteSVG() don't mark the peak: %s", svg)
+	}
+}
@@ -0,0 +1,211 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CoefficientPeriod the transformer ratios of the hours starting from From up to To,
+// zero To is an open period
+type CoefficientPeriod struct {
+	From time.Time
+	To   time.Time
+	CT   float64 // current transformer ratio
+	VT   float64 // voltage transformer ratio
+}
+
+// CoefficientUse the coefficient applied to consecutive hours
+type CoefficientUse struct {
+	From        time.Time // the first hour start
+	To          time.Time // the last hour end
+	Coefficient float64
+	Hours       int
+}
+
+// Coefficient return the power factory of the period
+func (p *CoefficientPeriod) Coefficient() float64 {
+	return p.CT * p.VT
+}
+
+// contains return true if the hour starting at t belongs to the period
+func (p *CoefficientPeriod) contains(t time.Time) bool {
+	return !t.Before(p.From) && (p.To.IsZero() || t.Before(p.To))
+}
+
+// LoadCoefficients reads the coefficient history from the *.csv file with rows "meter;from;to;ct;vt",
+// dates are "02.01.2006 15:04" in the meter time, an empty to is an open period,
+// ratios are numbers or like "200/5"
+func LoadCoefficients(filename string) (map[string][]*CoefficientPeriod, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(map[string][]*CoefficientPeriod)
+
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		row := strings.TrimSpace(string(line))
+		if row == "" || strings.HasPrefix(row, "meter") { // skips the header
+			continue
+		}
+
+		fields := strings.Split(row, ";")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("%s:%d: meter;from;to;ct;vt required", filename, i+1)
+		}
+
+		p, err := newCoefficientPeriod(fields[1], fields[2], fields[3], fields[4])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, i+1, err)
+		}
+
+		meter := strings.TrimSpace(fields[0])
+		history[meter] = append(history[meter], p)
+	}
+
+	if len(history) == 0 {
+		return nil, fmt.Errorf("%s: no coefficient periods found", filename)
+	}
+
+	for meter, periods := range history {
+		err = sortPeriods(periods)
+		if err != nil {
+			return nil, fmt.Errorf("%s: meter %s: %w", filename, meter, err)
+		}
+	}
+
+	return history, nil
+}
+
+// newCoefficientPeriod return the period of the dates "02.01.2006 15:04" and the ratios,
+// an empty to is an open period
+func newCoefficientPeriod(from, to, ct, vt string) (*CoefficientPeriod, error) {
+	p := &CoefficientPeriod{}
+
+	var err error
+	p.From, err = time.Parse("02.01.2006 15:04", strings.TrimSpace(from))
+	if err != nil {
+		return nil, err
+	}
+
+	if to = strings.TrimSpace(to); to != "" {
+		p.To, err = time.Parse("02.01.2006 15:04", to)
+		if err != nil {
+			return nil, err
+		}
+		if !p.To.After(p.From) {
+			return nil, errors.New("period should end after it starts")
+		}
+	}
+
+	p.CT, err = parseRatio(ct)
+	if err != nil {
+		return nil, err
+	}
+
+	p.VT, err = parseRatio(vt)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// sortPeriods sorts the periods by their start and checks that they don't overlap
+func sortPeriods(periods []*CoefficientPeriod) error {
+	sort.Slice(periods, func(i, j int) bool { return periods[i].From.Before(periods[j].From) })
+
+	for i := 1; i < len(periods); i++ {
+		if periods[i-1].To.IsZero() || periods[i-1].To.After(periods[i].From) {
+			return fmt.Errorf("periods from %s and %s overlap",
+				periods[i-1].From.Format("02.01.2006 15:04"), periods[i].From.Format("02.01.2006 15:04"))
+		}
+	}
+
+	return nil
+}
+
+// parseRatio return the transformer ratio of a number or a primary/secondary pair
+func parseRatio(s string) (float64, error) {
+	s = strings.Replace(strings.TrimSpace(s), ",", ".", -1)
+
+	values := strings.SplitN(s, "/", 2)
+
+	ratio, err := strconv.ParseFloat(values[0], 64)
+	if err != nil || ratio <= 0 {
+		return 0, fmt.Errorf("bad ratio %q", s)
+	}
+
+	if len(values) == 2 {
+		secondary, err := strconv.ParseFloat(values[1], 64)
+		if err != nil || secondary <= 0 {
+			return 0, fmt.Errorf("bad ratio %q", s)
+		}
+		ratio /= secondary
+	}
+
+	return ratio, nil
+}
+
+// coefficient return the power factory of the row with the register correction of the month hours
+func (a *App) coefficient(r *Row) float64 {
+	k := a.baseCoefficient(r)
+
+	start := r.Date.Add(-time.Hour)
+	if a.Correction != 0 && start.Year() == a.Year && int(start.Month()) == a.Month {
+		k *= a.Correction
+	}
+
+	return k
+}
+
+// baseCoefficient return the power factory of the row without the correction, Coefficient is used
+// for the hours outside the coefficient history
+func (a *App) baseCoefficient(r *Row) float64 {
+	if a.Scaled {
+		return 1
+	}
+
+	start := r.Date.Add(-time.Hour)
+	for _, p := range a.Coefficients {
+		if p.contains(start) {
+			return p.Coefficient()
+		}
+	}
+
+	return a.Coefficient
+}
+
+// CoefficientUsage return the coefficients applied to the hours of the month
+func (a *App) CoefficientUsage() []*CoefficientUse {
+	var usage []*CoefficientUse
+
+	for _, r := range a.Rows {
+		start := r.Date.Add(-time.Hour)
+		if start.Year() != a.Year || int(start.Month()) != a.Month {
+			continue
+		}
+
+		k := a.baseCoefficient(r)
+		if n := len(usage); n == 0 || usage[n-1].Coefficient != k {
+			usage = append(usage, &CoefficientUse{From: start, Coefficient: k})
+		}
+
+		u := usage[len(usage)-1]
+		u.To = r.Date
+		u.Hours++
+	}
+
+	return usage
+}
+
+// String return the use as text
+func (u *CoefficientUse) String() string {
+	return fmt.Sprintf("%v from %s to %s, %d hours", u.Coefficient, u.From.Format("02.01.2006 15:04"), u.To.Format("02.01.2006 15:04"), u.Hours)
+}
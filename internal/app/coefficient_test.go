@@ -0,0 +1,94 @@
+package app
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLoadCoefficients(t *testing.T) {
+	got, err := LoadCoefficients("testdata/coefficients_feb.csv")
+	if err != nil {
+		t.Fatalf("LoadCoefficients() error = %v", err)
+	}
+
+	periods := got["23456789"]
+	if len(periods) != 2 || periods[0].Coefficient() != 40 || periods[1].Coefficient() != 60 || !periods[1].To.IsZero() {
+		t.Errorf("LoadCoefficients() 23456789 = %v", periods)
+	}
+	if p := got["12345678"]; len(p) != 1 || p[0].Coefficient() != 2400 {
+		t.Errorf("LoadCoefficients() 12345678 = %v", p)
+	}
+
+	if _, err := LoadCoefficients("testdata/coefficients_overlap.csv"); err == nil {
+		t.Errorf("LoadCoefficients() of overlapping periods should fail")
+	}
+	if _, err := LoadCoefficients("testdata/peak_hours_feb.csv"); err == nil {
+		t.Errorf("LoadCoefficients() of a bad file should fail")
+	}
+}
+
+func TestParseRatio(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    float64
+		wantErr bool
+	}{
+		{s: "40", want: 40},
+		{s: "200/5", want: 40},
+		{s: " 6000/100 ", want: 60},
+		{s: "0,5", want: 0.5},
+		{s: "200/0", wantErr: true},
+		{s: "0", wantErr: true},
+		{s: "x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseRatio(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRatio() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseRatio() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_CoefficientUsage(t *testing.T) {
+	// 48 hours of 1 kWh, the ratio changes at 01.02.2020 10:00 and the history ends at 02.02.2020 00:00
+	values := make([]float64, 48)
+	for i := range values {
+		values[i] = 1
+	}
+
+	a := newApp(&Profile{Rows: hourlyRows(values)}, "", "", "", 1)
+	a.Coefficients = []*CoefficientPeriod{
+		{From: time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2020, 2, 1, 10, 0, 0, 0, time.UTC), CT: 40, VT: 1},
+		{From: time.Date(2020, 2, 1, 10, 0, 0, 0, time.UTC), To: time.Date(2020, 2, 2, 0, 0, 0, 0, time.UTC), CT: 60, VT: 1},
+	}
+
+	a.daily()
+	if want := 10*40.0 + 14*60 + 24*1; math.Abs(a.Total-want) > 1e-9 {
+		t.Errorf("daily() Total = %v, want %v", a.Total, want)
+	}
+
+	got := a.CoefficientUsage()
+	want := []string{
+		"40 from 01.02.2020 00:00 to 01.02.2020 10:00, 10 hours",
+		"60 from 01.02.2020 10:00 to 02.02.2020 00:00, 14 hours",
+		"1 from 02.02.2020 00:00 to 03.02.2020 00:00, 24 hours",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("CoefficientUsage() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("CoefficientUsage()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	if _, err := a.Reconcile(Register{Start: 0, End: 100}, 1); err == nil {
+		t.Errorf("Reconcile() with the coefficient changing within the month should fail")
+	}
+}
@@ -0,0 +1,212 @@
+package app
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sides of the compared datasets
+const (
+	sideLeft  = "left"
+	sideRight = "right"
+)
+
+// HourDiff the hour whose values differ or which is missing on a side
+type HourDiff struct {
+	Start   time.Time // the hour start
+	Left    float64
+	Right   float64
+	Delta   float64 // Right minus Left
+	Missing string  // the side without the hour
+}
+
+// MeterDiff the differences of a single meter
+type MeterDiff struct {
+	Meter        string
+	LeftTotal    float64
+	RightTotal   float64
+	Delta        float64 // RightTotal minus LeftTotal
+	MissingLeft  int     // hours of the right side only
+	MissingRight int     // hours of the left side only
+	Hours        []*HourDiff
+}
+
+// Open return the meters of a configurator *.html export, an 80020 *.xml file,
+// a directory of 80020 files or a *.zip archive of them, the coefficient of def scales the export only
+func Open(name, companyName string, def Mapping) ([]*App, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		filenames, err := filepath.Glob(filepath.Join(name, "*.xml"))
+		if err != nil {
+			return nil, err
+		}
+		if len(filenames) == 0 {
+			return nil, fmt.Errorf("%s: no *.xml files found", name)
+		}
+		return NewXML(filenames, companyName, def, nil)
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".zip":
+		return NewZip(name, companyName, def, nil)
+	case ".xml":
+		return NewXML([]string{name}, companyName, def, nil)
+	}
+
+	return NewAll(name, companyName, def, nil)
+}
+
+// Compare return the differences of the scaled hourly values of the meters with the same serial number,
+// an 80020 accountpoint code read without the contract matches the serial at its end,
+// the hours differing by no more than threshold kWh are left out
+func Compare(left, right []*App, threshold float64) []*MeterDiff {
+	lefts := hourlyByMeter(left)
+	rights := hourlyByMeter(right)
+	matchSerials(lefts, rights)
+
+	var meters []string
+	for meter := range lefts {
+		meters = append(meters, meter)
+	}
+	for meter := range rights {
+		if _, ok := lefts[meter]; !ok {
+			meters = append(meters, meter)
+		}
+	}
+	sort.Strings(meters)
+
+	var diffs []*MeterDiff
+
+	for _, meter := range meters {
+		l, r := lefts[meter], rights[meter]
+		d := &MeterDiff{Meter: meter}
+
+		var starts []time.Time
+		for start, v := range l {
+			d.LeftTotal += v
+			starts = append(starts, start)
+		}
+		for start, v := range r {
+			d.RightTotal += v
+			if _, ok := l[start]; !ok {
+				starts = append(starts, start)
+			}
+		}
+		sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+		for _, start := range starts {
+			lv, lok := l[start]
+			rv, rok := r[start]
+
+			h := &HourDiff{Start: start, Left: lv, Right: rv, Delta: rv - lv}
+			switch {
+			case !lok:
+				h.Missing = sideLeft
+				d.MissingLeft++
+			case !rok:
+				h.Missing = sideRight
+				d.MissingRight++
+			case math.Abs(h.Delta) <= threshold:
+				continue
+			}
+
+			d.Hours = append(d.Hours, h)
+		}
+
+		d.Delta = d.RightTotal - d.LeftTotal
+		diffs = append(diffs, d)
+	}
+
+	return diffs
+}
+
+// matchSerials renames the meter of one side ending with the only unmatched meter of the other side
+// to the shorter of them, e.g. the accountpoint code 9876543223456789 to the serial 23456789
+func matchSerials(lefts, rights map[string]map[time.Time]float64) {
+	for l := range lefts {
+		if _, ok := rights[l]; ok {
+			continue
+		}
+
+		var found []string
+		for r := range rights {
+			if _, ok := lefts[r]; !ok && (strings.HasSuffix(l, r) || strings.HasSuffix(r, l)) {
+				found = append(found, r)
+			}
+		}
+		if len(found) != 1 {
+			continue
+		}
+
+		r := found[0]
+		if len(r) < len(l) {
+			lefts[r] = lefts[l]
+			delete(lefts, l)
+		} else {
+			rights[l] = rights[r]
+			delete(rights, r)
+		}
+	}
+}
+
+// hourlyByMeter return the scaled hourly values by the hour start of each meter
+func hourlyByMeter(apps []*App) map[string]map[time.Time]float64 {
+	meters := make(map[string]map[time.Time]float64)
+
+	for _, a := range apps {
+		hours, ok := meters[a.Meter]
+		if !ok {
+			hours = make(map[time.Time]float64)
+			meters[a.Meter] = hours
+		}
+
+		for _, r := range a.Rows {
+			hours[r.Date.Add(-time.Hour)] = r.PPlus * a.coefficient(r)
+		}
+	}
+
+	return meters
+}
+
+// WriteDiffCSV writes the hours of the differences separated by semicolons
+func WriteDiffCSV(w io.Writer, diffs []*MeterDiff) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+
+	err := cw.Write([]string{"meter", "date", "hour", "left", "right", "delta", "missing"})
+	if err != nil {
+		return err
+	}
+
+	for _, d := range diffs {
+		for _, h := range d.Hours {
+			err = cw.Write([]string{
+				d.Meter,
+				h.Start.Format("02.01.2006"),
+				fmt.Sprintf("%02d", h.Start.Hour()),
+				decimal(h.Left, 4),
+				decimal(h.Right, 4),
+				decimal(h.Delta, 4),
+				h.Missing,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	left := newApp(&Profile{Meter: "1", Rows: hourlyRows([]float64{1, 2, 3, 4})}, "", "", "", 1)
+	// the second hour differs, the fourth is missing and the fifth is extra
+	right := newApp(&Profile{Meter: "1", Rows: hourlyRows([]float64{1, 2.5, 3.0005, 0, 5})}, "", "", "", 1)
+	right.Rows = append(right.Rows[:3], right.Rows[4:]...)
+	other := newApp(&Profile{Meter: "2", Rows: hourlyRows([]float64{7})}, "", "", "", 2)
+
+	got := Compare([]*App{left}, []*App{right, other}, 0.001)
+
+	if len(got) != 2 || got[0].Meter != "1" || got[1].Meter != "2" {
+		t.Fatalf("Compare() = %v", got)
+	}
+
+	d := got[0]
+	if d.LeftTotal != 10 || math.Abs(d.RightTotal-11.5005) > 1e-9 || d.MissingLeft != 1 || d.MissingRight != 1 {
+		t.Errorf("Compare() totals = %+v", d)
+	}
+
+	tests := []struct {
+		hour    int
+		delta   float64
+		missing string
+	}{
+		{hour: 1, delta: 0.5},
+		{hour: 3, delta: -4, missing: "right"},
+		{hour: 4, delta: 5, missing: "left"},
+	}
+	if len(d.Hours) != len(tests) {
+		t.Fatalf("Compare() hours = %d, want %d", len(d.Hours), len(tests))
+	}
+	for i, tt := range tests {
+		h := d.Hours[i]
+		if !h.Start.Equal(time.Date(2020, 2, 1, tt.hour, 0, 0, 0, time.UTC)) || h.Delta != tt.delta || h.Missing != tt.missing {
+			t.Errorf("Compare() hour %d = %+v, want %+v", i, h, tt)
+		}
+	}
+
+	if o := got[1]; o.LeftTotal != 0 || o.RightTotal != 14 || o.MissingLeft != 1 {
+		t.Errorf("Compare() meter of the right side only = %+v", o)
+	}
+
+	buff := new(bytes.Buffer)
+	if err := WriteDiffCSV(buff, got); err != nil {
+		t.Fatalf("WriteDiffCSV() error = %v", err)
+	}
+	if !strings.HasPrefix(buff.String(), "meter;date;hour;left;right;delta;missing\n1;01.02.2020;01;2,0000;2,5000;0,5000;\n") {
+		t.Errorf("WriteDiffCSV() = %s", buff.String())
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dirName := t.TempDir()
+
+	head := newHead(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), "98765432", "OOO STAR", "23456789")
+	body, err := newBody(make([]float64, 24))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buff, err := toBuffer(head, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := toFile(buff, dirName, head); err != nil {
+		t.Fatal(err)
+	}
+
+	zipName := path.Join(t.TempDir(), "80020.zip")
+	f, err := os.Create(zipName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("80020-02-2020/" + fileName(head))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(buff.Bytes())
+	zw.Close()
+	f.Close()
+
+	def := Mapping{Contract: "98765432", Coefficient: 1}
+
+	tests := []struct {
+		name string
+		rows int
+	}{
+		{name: "testdata/23456789_feb.html", rows: 29 * 24},
+		{name: dirName, rows: 24},
+		{name: path.Join(dirName, fileName(head)), rows: 24},
+		{name: zipName, rows: 24},
+	}
+	for _, tt := range tests {
+		t.Run(path.Base(tt.name), func(t *testing.T) {
+			got, err := Open(tt.name, "", def)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if len(got) != 1 || got[0].Meter != "23456789" || len(got[0].Rows) != tt.rows {
+				t.Errorf("Open() = %d meters", len(got))
+			}
+		})
+	}
+
+	if _, err := Open(t.TempDir(), "", def); err == nil {
+		t.Errorf("Open() of an empty directory should fail")
+	}
+}
+
+func TestCompare_scaled(t *testing.T) {
+	// the coefficient scales the export, the 80020 files written from it are already scaled
+	def := Mapping{Contract: "98765432", Coefficient: 40}
+
+	apps, err := Open("testdata/23456789_feb.html", "OOO STAR", def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirName, err := RunAllIn(t.TempDir(), apps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	left, err := Open("testdata/23456789_feb.html", "", def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// without the contract the accountpoint codes are matched by the serial at their end
+	for _, contract := range []string{"98765432", ""} {
+		right, err := Open(dirName, "", Mapping{Contract: contract, Coefficient: 40})
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+
+		// the 80020 values are rounded to 0.1 kWh
+		got := Compare(left, right, 0.05)
+		if len(got) != 1 {
+			t.Fatalf("Compare() with contract %q = %d meters, want 1", contract, len(got))
+		}
+		if got[0].Meter != "23456789" || len(got[0].Hours) != 0 || math.Abs(got[0].Delta) > 1 {
+			t.Errorf("Compare() with contract %q = %s, %d hours differ", contract, got[0].Meter, len(got[0].Hours))
+		}
+	}
+}
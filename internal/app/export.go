@@ -0,0 +1,217 @@
+package app
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Layouts of the exported profile
+const (
+	LayoutLong   = "long"   // one row per hour
+	LayoutMatrix = "matrix" // one row per day and one column per hour
+)
+
+// Statuses of the exported hours
+const (
+	statusOK        = "ok"
+	statusEstimated = "estimated"
+	statusMissing   = "missing"
+)
+
+// Export spreadsheet formats written next to the 80020 files
+type Export struct {
+	Formats []string // csv and xlsx
+	Layout  string
+}
+
+// NewExport return Export of the formats and the layout, empty layout is the long one
+func NewExport(formats []string, layout string) (*Export, error) {
+	if layout == "" {
+		layout = LayoutLong
+	}
+	if layout != LayoutLong && layout != LayoutMatrix {
+		return nil, fmt.Errorf("bad layout %s: long or matrix required", layout)
+	}
+
+	e := &Export{Layout: layout}
+
+	for _, f := range formats {
+		switch f {
+		case "":
+			continue
+		case "csv", "xlsx":
+			e.Formats = append(e.Formats, f)
+		default:
+			return nil, fmt.Errorf("bad export format %s: csv or xlsx required", f)
+		}
+	}
+
+	if len(e.Formats) == 0 {
+		return nil, nil
+	}
+
+	return e, nil
+}
+
+// nameChars matches the characters of a meter or group name allowed in file names
+var nameChars = regexp.MustCompile(`^[\p{L}\p{N}_.-]+$`)
+
+// badNameChar matches a character not allowed in file names
+var badNameChar = regexp.MustCompile(`[^\p{L}\p{N}_.-]`)
+
+// ValidName reports whether the meter or group name can be a part of a file name
+func ValidName(name string) bool {
+	return nameChars.MatchString(name) && !strings.Contains(name, "..")
+}
+
+// exportName return the name of the exported profile file, the characters of the meter
+// not allowed in file names are replaced by _
+func exportName(meter, format string) string {
+	meter = badNameChar.ReplaceAllString(filepath.Base(meter), "_")
+	meter = strings.ReplaceAll(meter, "..", "_")
+
+	return fmt.Sprintf("profile_%s.%s", meter, format)
+}
+
+// export writes the profile in each format to the directory and returns the file names
+func (a *App) export(dirName string) ([]string, error) {
+	if a.Export == nil {
+		return nil, nil
+	}
+
+	table := a.Table(a.Export.Layout)
+
+	var files []string
+
+	for _, format := range a.Export.Formats {
+		name := exportName(a.Meter, format)
+
+		f, err := os.Create(path.Join(dirName, name))
+		if err != nil {
+			return nil, err
+		}
+
+		switch format {
+		case "csv":
+			err = writeTableCSV(f, table)
+		case "xlsx":
+			err = writeXLSX(f, a.Meter, table)
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		err = f.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, name)
+	}
+
+	return files, nil
+}
+
+// Table return the scaled hourly profile of the month with the header row,
+// values are float64 and empty cells are nil
+func (a *App) Table(layout string) [][]interface{} {
+	byStart := make(map[time.Time]*Row)
+	for _, r := range a.Rows {
+		byStart[r.Date.Add(-time.Hour)] = r
+	}
+
+	from := time.Date(a.Year, time.Month(a.Month), 1, 0, 0, 0, 0, time.UTC)
+
+	if layout == LayoutMatrix {
+		header := []interface{}{"date"}
+		for h := 0; h < 24; h++ {
+			header = append(header, fmt.Sprintf("%02d", h))
+		}
+		table := [][]interface{}{append(header, "total")}
+
+		for d := 0; d < a.DaysInMonth; d++ {
+			day := from.AddDate(0, 0, d)
+			row := []interface{}{day.Format("02.01.2006")}
+
+			var total float64
+			for h := 0; h < 24; h++ {
+				r, ok := byStart[day.Add(time.Duration(h)*time.Hour)]
+				if !ok {
+					row = append(row, nil)
+					continue
+				}
+				p := r.PPlus * a.coefficient(r)
+				total += p
+				row = append(row, p)
+			}
+
+			table = append(table, append(row, total))
+		}
+
+		return table
+	}
+
+	table := [][]interface{}{{"date", "hour", "p_plus", "p_minus", "q_plus", "q_minus", "status"}}
+
+	for t := from; t.Before(from.AddDate(0, 0, a.DaysInMonth)); t = t.Add(time.Hour) {
+		r, ok := byStart[t]
+		if !ok {
+			table = append(table, []interface{}{t.Format("02.01.2006"), fmt.Sprintf("%02d", t.Hour()), nil, nil, nil, nil, statusMissing})
+			continue
+		}
+
+		k := a.coefficient(r)
+		status := statusOK
+		if r.Estimated() {
+			status = statusEstimated
+		}
+
+		table = append(table, []interface{}{
+			t.Format("02.01.2006"),
+			fmt.Sprintf("%02d", t.Hour()),
+			r.PPlus * k,
+			r.PMinus * k,
+			r.QPlus * k,
+			r.QMinus * k,
+			status,
+		})
+	}
+
+	return table
+}
+
+// writeTableCSV writes the table separated by semicolons with decimal commas
+func writeTableCSV(w io.Writer, table [][]interface{}) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+
+	for _, row := range table {
+		record := make([]string, len(row))
+		for i, v := range row {
+			switch v := v.(type) {
+			case float64:
+				record[i] = decimal(v, 4)
+			case nil:
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+
+		err := cw.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
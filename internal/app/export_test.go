@@ -0,0 +1,129 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestNewExport(t *testing.T) {
+	tests := []struct {
+		name    string
+		formats []string
+		layout  string
+		want    *Export
+		wantErr bool
+	}{
+		{name: "nothing", formats: []string{""}},
+		{name: "long by default", formats: []string{"csv"}, want: &Export{Formats: []string{"csv"}, Layout: LayoutLong}},
+		{name: "matrix", formats: []string{"csv", "xlsx"}, layout: "matrix", want: &Export{Formats: []string{"csv", "xlsx"}, Layout: LayoutMatrix}},
+		{name: "bad format", formats: []string{"pdf"}, wantErr: true},
+		{name: "bad layout", formats: []string{"csv"}, layout: "wide", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewExport(tt.formats, tt.layout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewExport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || got != nil && (strings.Join(got.Formats, ",") != strings.Join(tt.want.Formats, ",") || got.Layout != tt.want.Layout) {
+				t.Errorf("NewExport() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_Table(t *testing.T) {
+	// the first day without the third hour, the second hour is estimated
+	rows := hourlyRows([]float64{1, 2, 3, 4})
+	rows[1].Note = "estimated"
+	rows = append(rows[:2], rows[3:]...)
+
+	a := newApp(&Profile{Rows: rows}, "", "", "23456789", 2)
+
+	tests := []struct {
+		layout string
+		want   []string
+	}{
+		{
+			layout: LayoutLong,
+			want: []string{
+				"date;hour;p_plus;p_minus;q_plus;q_minus;status",
+				"01.02.2020;00;2,0000;0,0000;1,0000;0,0000;ok",
+				"01.02.2020;01;4,0000;0,0000;2,0000;0,0000;estimated",
+				"01.02.2020;02;;;;;missing",
+				"01.02.2020;03;8,0000;0,0000;4,0000;0,0000;ok",
+			},
+		},
+		{
+			layout: LayoutMatrix,
+			want: []string{
+				"date;00;01;02;03;04;05;06;07;08;09;10;11;12;13;14;15;16;17;18;19;20;21;22;23;total",
+				"01.02.2020;2,0000;4,0000;;8,0000;;;;;;;;;;;;;;;;;;;;;14,0000",
+				"02.02.2020;;;;;;;;;;;;;;;;;;;;;;;;;0,0000",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			table := a.Table(tt.layout)
+
+			buff := new(bytes.Buffer)
+			if err := writeTableCSV(buff, table); err != nil {
+				t.Fatalf("writeTableCSV() error = %v", err)
+			}
+
+			lines := strings.Split(buff.String(), "\n")
+			for i, want := range tt.want {
+				if lines[i] != want {
+					t.Errorf("Table() line %d = %s, want %s", i, lines[i], want)
+				}
+			}
+		})
+	}
+
+	if got := len(a.Table(LayoutLong)); got != 29*24+1 {
+		t.Errorf("Table() long rows = %d, want %d", got, 29*24+1)
+	}
+}
+
+func Test_exportName(t *testing.T) {
+	tests := []struct {
+		meter string
+		want  string
+	}{
+		{meter: "23456789", want: "profile_23456789.csv"},
+		{meter: "../../x", want: "profile_x.csv"},
+		{meter: `..\x`, want: "profile___x.csv"},
+		{meter: "..", want: "profile__.csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.meter, func(t *testing.T) {
+			if got := exportName(tt.meter, "csv"); got != tt.want {
+				t.Errorf("exportName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_export(t *testing.T) {
+	a := newApp(&Profile{Rows: hourlyRows([]float64{1, 2})}, "", "", "23456789", 1)
+	a.Export = &Export{Formats: []string{"csv", "xlsx"}, Layout: LayoutLong}
+
+	dirName := t.TempDir()
+	got, err := a.export(dirName)
+	if err != nil {
+		t.Fatalf("export() error = %v", err)
+	}
+
+	if strings.Join(got, ",") != "profile_23456789.csv,profile_23456789.xlsx" {
+		t.Errorf("export() = %v", got)
+	}
+	for _, name := range got {
+		if _, err := os.Stat(path.Join(dirName, name)); err != nil {
+			t.Errorf("export() %v", err)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"text/template"
 	"time"
@@ -23,6 +24,9 @@ type Head struct {
 	Meter       string
 	Month       string
 	Year        string
+	INN         string // the sender INN
+	AreaINN     string // the area INN, zeros if empty
+	AreaName    string // the area name, 0 if empty
 }
 
 // Body is used to create a template body
@@ -32,6 +36,13 @@ type Body struct {
 	Value       string
 }
 
+// Point is used to create a template accountpoint
+type Point struct {
+	Contract string
+	Meter    string
+	Body     []*Body
+}
+
 // newHead return Head
 func newHead(date time.Time, contract, companyName, meter string) *Head {
 	return &Head{
@@ -74,14 +85,24 @@ func endPeriod(i int) string {
 // zeroValue return 0 if v == 0.0
 func zeroValue(v float64) string {
 	if v != 0 {
-		return strings.Replace(fmt.Sprintf("%.1f", v), ".", ",", 1)
+		return decimal(v, 1)
 	}
 
 	return "0"
 }
 
-// toBuffer return one day template
+// decimal return v with prec digits after the decimal comma
+func decimal(v float64, prec int) string {
+	return strings.Replace(fmt.Sprintf("%.*f", prec, v), ".", ",", 1)
+}
+
+// toBuffer return one day template with a single accountpoint
 func toBuffer(head *Head, body []*Body) (*bytes.Buffer, error) {
+	return toBufferPoints(head, []*Point{{Contract: head.Contract, Meter: head.Meter, Body: body}})
+}
+
+// toBufferPoints return one day template with an accountpoint for each point
+func toBufferPoints(head *Head, points []*Point) (*bytes.Buffer, error) {
 	tmpl, err := template.New("daily").ParseFS(templateFS, "template/daily_xml.tmpl")
 	if err != nil {
 		return nil, err
@@ -94,9 +115,11 @@ func toBuffer(head *Head, body []*Body) (*bytes.Buffer, error) {
 		return nil, err
 	}
 
-	err = tmpl.ExecuteTemplate(buff, "body", body)
-	if err != nil {
-		return nil, err
+	for _, p := range points {
+		err = tmpl.ExecuteTemplate(buff, "accountpoint", p)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	err = tmpl.ExecuteTemplate(buff, "tail", nil)
@@ -107,12 +130,14 @@ func toBuffer(head *Head, body []*Body) (*bytes.Buffer, error) {
 	return buff, nil
 }
 
+// fileName return the name of one day file
+func fileName(h *Head) string {
+	return fmt.Sprintf("80020_001_%s_%02s%02s%s.xml", h.Contract, h.Day, h.Month, h.Year)
+}
+
 // toFile write one day buffer to file
 func toFile(buff *bytes.Buffer, dirName string, h *Head) error {
-
-	newFilename := fmt.Sprintf("80020_001_%s_%02s%02s%s.xml", h.Contract, h.Day, h.Month, h.Year)
-
-	f, err := os.Create(path.Join(dirName, newFilename))
+	f, err := os.Create(path.Join(dirName, fileName(h)))
 	if err != nil {
 		return err
 	}
@@ -126,12 +151,17 @@ func toFile(buff *bytes.Buffer, dirName string, h *Head) error {
 	return nil
 }
 
-// createDir create a directory if it does not exist
-func createDir(month, year int) (string, error) {
-	dirName := fmt.Sprintf("80020-%02d-%d", month, year)
+// DirName return the directory of the month files
+func DirName(month, year int) string {
+	return fmt.Sprintf("80020-%02d-%d", month, year)
+}
+
+// createDir create the month directory under root if it does not exist
+func createDir(root string, month, year int) (string, error) {
+	dirName := filepath.Join(root, DirName(month, year))
 
 	if _, err := os.Stat(dirName); os.IsNotExist(err) {
-		err := os.Mkdir(dirName, 0766)
+		err := os.MkdirAll(dirName, 0766)
 		if err != nil {
 			return "", err
 		}
@@ -271,7 +271,7 @@ func Test_createDir(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := createDir(tt.args.month, tt.args.year)
+			got, err := createDir(".", tt.args.month, tt.args.year)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("createDir() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -0,0 +1,252 @@
+package app
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// trendDays number of the last history days used to adjust the profile level
+const trendDays = 14
+
+// Forecast weekday and hour profile of the history
+type Forecast struct {
+	Calendar *Calendar
+	Trend    float64 // the level of the last history days against the profile
+
+	profile map[[2]int]float64 // by the profile weekday and the hour
+	hourly  [24]float64        // by the hour when the weekday has no data
+	last    time.Time          // the last history hour start
+}
+
+// PlanHour planned consumption of a single hour
+type PlanHour struct {
+	Start time.Time
+	Value float64
+}
+
+// Plan planned hourly consumption of a meter
+type Plan struct {
+	Contract    string
+	CompanyName string
+	Meter       string
+	Hours       []*PlanHour
+}
+
+// Backtest plan-vs-actual errors of a month
+type Backtest struct {
+	Plan    *Plan
+	Hours   int
+	Actual  float64 // the actual consumption of the compared hours
+	Planned float64
+	MAE     float64 // the mean absolute error in kWh
+	WAPE    float64 // the absolute errors against the actual consumption in percent
+}
+
+// NewForecast return the profile of the scaled hourly values of the history,
+// cal nil is the bundled production calendar
+func NewForecast(history []*App, cal *Calendar) (*Forecast, error) {
+	f := &Forecast{Calendar: cal, Trend: 1, profile: make(map[[2]int]float64)}
+
+	sums := make(map[[2]int]float64)
+	counts := make(map[[2]int]int)
+	var hourlySums [24]float64
+	var hourlyCounts [24]int
+
+	var hours []hourValue
+
+	for _, a := range history {
+		for _, r := range a.Rows {
+			start := r.Date.Add(-time.Hour)
+			v := r.PPlus * a.coefficient(r)
+
+			key := f.key(start)
+			sums[key] += v
+			counts[key]++
+			hourlySums[start.Hour()] += v
+			hourlyCounts[start.Hour()]++
+
+			hours = append(hours, hourValue{Start: start, Value: v})
+
+			if start.After(f.last) {
+				f.last = start
+			}
+		}
+	}
+
+	if len(hours) == 0 {
+		return nil, errors.New("bad data: history without rows")
+	}
+
+	for key, sum := range sums {
+		f.profile[key] = sum / float64(counts[key])
+	}
+	for h := range hourlySums {
+		if hourlyCounts[h] != 0 {
+			f.hourly[h] = hourlySums[h] / float64(hourlyCounts[h])
+		}
+	}
+
+	// the recent level against the profile follows the growth or the decline of consumption
+	from := f.last.AddDate(0, 0, -trendDays)
+	var actual, expected float64
+	for _, h := range hours {
+		if h.Start.After(from) {
+			actual += h.Value
+			expected += f.value(h.Start)
+		}
+	}
+	if expected != 0 {
+		f.Trend = math.Max(0.5, math.Min(1.5, actual/expected))
+	}
+
+	return f, nil
+}
+
+// key return the profile key of the hour starting at t
+func (f *Forecast) key(t time.Time) [2]int {
+	return [2]int{int(f.Calendar.profileDay(t)), t.Hour()}
+}
+
+// value return the profile value of the hour starting at t without the trend
+func (f *Forecast) value(t time.Time) float64 {
+	if v, ok := f.profile[f.key(t)]; ok {
+		return v
+	}
+
+	return f.hourly[t.Hour()]
+}
+
+// Next return the day following the last history hour
+func (f *Forecast) Next() time.Time {
+	return time.Date(f.last.Year(), f.last.Month(), f.last.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+// Plan return planned hours from the start of from to the start of to
+func (f *Forecast) Plan(from, to time.Time, contract, companyName, meter string) *Plan {
+	p := &Plan{Contract: contract, CompanyName: companyName, Meter: meter}
+
+	for t := from; t.Before(to); t = t.Add(time.Hour) {
+		p.Hours = append(p.Hours, &PlanHour{Start: t, Value: f.value(t) * f.Trend})
+	}
+
+	return p
+}
+
+// DayPlan return planned hours of the day
+func (f *Forecast) DayPlan(day time.Time, contract, companyName, meter string) *Plan {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	return f.Plan(from, from.AddDate(0, 0, 1), contract, companyName, meter)
+}
+
+// MonthPlan return planned hours of the month
+func (f *Forecast) MonthPlan(year int, month time.Month, contract, companyName, meter string) *Plan {
+	from := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+
+	return f.Plan(from, from.AddDate(0, 1, 0), contract, companyName, meter)
+}
+
+// NewBacktest return the errors of the plan made by the history for the month of actual
+func NewBacktest(history []*App, actual *App, cal *Calendar) (*Backtest, error) {
+	f, err := NewForecast(history, cal)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Backtest{Plan: f.MonthPlan(actual.Year, time.Month(actual.Month), actual.Contract, actual.CompanyName, actual.Meter)}
+
+	planned := make(map[time.Time]float64)
+	for _, h := range b.Plan.Hours {
+		planned[h.Start] = h.Value
+	}
+
+	var absErr float64
+
+	for _, r := range actual.Rows {
+		start := r.Date.Add(-time.Hour)
+		p, ok := planned[start]
+		if !ok {
+			continue
+		}
+
+		v := r.PPlus * actual.coefficient(r)
+
+		b.Hours++
+		b.Actual += v
+		b.Planned += p
+		absErr += math.Abs(p - v)
+	}
+
+	if b.Hours == 0 {
+		return nil, errors.New("bad data: no actual hours in the planned month")
+	}
+
+	b.MAE = absErr / float64(b.Hours)
+	if b.Actual != 0 {
+		b.WAPE = absErr / b.Actual * 100
+	}
+
+	return b, nil
+}
+
+// WriteXML writes one 80020 file per planned day
+func (p *Plan) WriteXML(dirName string) error {
+	days := make(map[time.Time][]float64)
+	var dates []time.Time
+
+	for _, h := range p.Hours {
+		day := time.Date(h.Start.Year(), h.Start.Month(), h.Start.Day(), 0, 0, 0, 0, time.UTC)
+		if _, ok := days[day]; !ok {
+			days[day] = make([]float64, 24)
+			dates = append(dates, day)
+		}
+		days[day][h.Start.Hour()] = h.Value
+	}
+
+	for _, day := range dates {
+		head := newHead(day, p.Contract, p.CompanyName, p.Meter)
+
+		body, err := newBody(days[day])
+		if err != nil {
+			return err
+		}
+
+		buff, err := toBuffer(head, body)
+		if err != nil {
+			return err
+		}
+
+		err = toFile(buff, dirName, head)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteCSV writes planned hours separated by semicolons
+func (p *Plan) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+
+	err := cw.Write([]string{"date", "hour", "p_plus"})
+	if err != nil {
+		return err
+	}
+
+	for _, h := range p.Hours {
+		err = cw.Write([]string{h.Start.Format("02.01.2006"), fmt.Sprintf("%02d", h.Start.Hour()), decimal(h.Value, 2)})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// workingRows return hourly rows from the start of from to the start of to,
+// working hours of the working days consume 10 kWh and other hours 2 kWh
+func workingRows(from, to time.Time, scale float64) []*Row {
+	var rows []*Row
+	for t := from; t.Before(to); t = t.Add(time.Hour) {
+		v := 2.0
+		if (*Calendar)(nil).IsWorkingDay(t) && t.Hour() >= 8 && t.Hour() < 18 {
+			v = 10
+		}
+		rows = append(rows, &Row{PPlus: v * scale, Date: t.Add(time.Hour)})
+	}
+
+	return rows
+}
+
+func TestForecast_Plan(t *testing.T) {
+	history := &App{
+		Coefficient: 1,
+		Rows:        workingRows(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), 1),
+	}
+
+	f, err := NewForecast([]*App{history}, nil)
+	if err != nil {
+		t.Fatalf("NewForecast() error = %v", err)
+	}
+
+	if !f.Next().Equal(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Next() = %v, want 01.02.2020", f.Next())
+	}
+	if math.Abs(f.Trend-1) > 1e-9 {
+		t.Errorf("Trend = %v, want 1", f.Trend)
+	}
+
+	plan := f.MonthPlan(2020, time.February, "98765432", "OOO STAR", "23456789")
+	if len(plan.Hours) != 29*24 {
+		t.Fatalf("MonthPlan() hours = %d, want %d", len(plan.Hours), 29*24)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want float64
+	}{
+		{name: "working hour", at: time.Date(2020, 2, 25, 10, 0, 0, 0, time.UTC), want: 10},
+		{name: "night", at: time.Date(2020, 2, 25, 2, 0, 0, 0, time.UTC), want: 2},
+		{name: "moved holiday", at: time.Date(2020, 2, 24, 10, 0, 0, 0, time.UTC), want: 2},
+		{name: "saturday", at: time.Date(2020, 2, 22, 10, 0, 0, 0, time.UTC), want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, h := range plan.Hours {
+				if h.Start.Equal(tt.at) && math.Abs(h.Value-tt.want) > 1e-9 {
+					t.Errorf("MonthPlan() %v = %v, want %v", tt.at, h.Value, tt.want)
+				}
+			}
+		})
+	}
+
+	if day := f.DayPlan(f.Next(), "", "", ""); len(day.Hours) != 24 {
+		t.Errorf("DayPlan() hours = %d, want 24", len(day.Hours))
+	}
+
+	if _, err := NewForecast(nil, nil); err == nil {
+		t.Errorf("NewForecast() without history should fail")
+	}
+}
+
+func TestForecast_Trend(t *testing.T) {
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := workingRows(from, time.Date(2020, 1, 18, 0, 0, 0, 0, time.UTC), 1)
+	// the last two weeks consume a fifth more
+	rows = append(rows, workingRows(time.Date(2020, 1, 18, 0, 0, 0, 0, time.UTC), time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), 1.2)...)
+
+	f, err := NewForecast([]*App{{Coefficient: 1, Rows: rows}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Trend <= 1 {
+		t.Errorf("Trend = %v, want growth", f.Trend)
+	}
+}
+
+func TestNewBacktest(t *testing.T) {
+	history := &App{
+		Coefficient: 1,
+		Rows:        workingRows(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), 1),
+	}
+	actual := &App{
+		Coefficient: 1,
+		Month:       2,
+		Year:        2020,
+		Rows:        workingRows(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), 1),
+	}
+
+	got, err := NewBacktest([]*App{history}, actual, nil)
+	if err != nil {
+		t.Fatalf("NewBacktest() error = %v", err)
+	}
+
+	if got.Hours != 29*24 || got.MAE > 1e-9 || got.WAPE > 1e-9 {
+		t.Errorf("NewBacktest() = %+v, want exact plan", got)
+	}
+
+	actual.Rows[10].PPlus += 29 * 24
+	got, err = NewBacktest([]*App{history}, actual, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got.MAE-1) > 1e-9 {
+		t.Errorf("NewBacktest() MAE = %v, want 1", got.MAE)
+	}
+}
+
+func TestPlan_Write(t *testing.T) {
+	plan := &Plan{Contract: "98765432", CompanyName: "OOO STAR", Meter: "23456789"}
+	for i := 0; i < 24; i++ {
+		plan.Hours = append(plan.Hours, &PlanHour{Start: time.Date(2020, 3, 2, i, 0, 0, 0, time.UTC), Value: 1.25})
+	}
+
+	dirName := t.TempDir()
+	if err := plan.WriteXML(dirName); err != nil {
+		t.Fatalf("WriteXML() error = %v", err)
+	}
+	data, err := os.ReadFile(path.Join(dirName, "80020_001_98765432_02032020.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`<value status="0">1,2</value>`)) {
+		t.Errorf("WriteXML() don't contain the planned value")
+	}
+
+	buff := new(bytes.Buffer)
+	if err := plan.WriteCSV(buff); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	if !strings.HasPrefix(buff.String(), "date;hour;p_plus\n02.03.2020;00;1,25\n") {
+		t.Errorf("WriteCSV() = %q", buff.String())
+	}
+}
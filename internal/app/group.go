@@ -0,0 +1,187 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Group hourly combination of several meters emitted as its own accountpoint
+type Group struct {
+	Name     string // the accountpoint serial number of the combination
+	Contract string
+	Members  []*GroupMember
+}
+
+// GroupMember a meter of the group added with the sign 1 or subtracted with -1
+type GroupMember struct {
+	Meter string
+	Sign  float64
+}
+
+// ParseGroups return groups from a string like "name=contract:+serial-serial;name=contract:+serial+serial"
+func ParseGroups(s string) ([]*Group, error) {
+	var groups []*Group
+
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("bad group %q: name=contract:+serial-serial required", item)
+		}
+
+		if !ValidName(kv[0]) {
+			return nil, fmt.Errorf("bad group %q: the name should consist of letters, digits, _, - and .", item)
+		}
+
+		g := &Group{Name: kv[0]}
+
+		terms := kv[1]
+		if i := strings.Index(terms, ":"); i != -1 {
+			g.Contract, terms = terms[:i], terms[i+1:]
+		}
+
+		for terms != "" {
+			sign := 1.0
+			switch terms[0] {
+			case '+':
+			case '-':
+				sign = -1
+			default:
+				return nil, fmt.Errorf("bad group %q: each serial should follow + or -", item)
+			}
+			terms = terms[1:]
+
+			end := strings.IndexAny(terms, "+-")
+			if end == -1 {
+				end = len(terms)
+			}
+
+			meter := strings.TrimSpace(terms[:end])
+			if meter == "" {
+				return nil, fmt.Errorf("bad group %q: serial required", item)
+			}
+			g.Members = append(g.Members, &GroupMember{Meter: meter, Sign: sign})
+
+			terms = terms[end:]
+		}
+
+		if len(g.Members) == 0 {
+			return nil, fmt.Errorf("bad group %q: there should be meters", item)
+		}
+
+		groups = append(groups, g)
+	}
+
+	return groups, nil
+}
+
+// NewGroup return App of the scaled hourly values of the members combined with their signs,
+// every member should have a value of each hour of the others,
+// the group takes the schedule, the calendar, the peak hours and the export of the first member
+// and its contract if the group has no contract
+func NewGroup(g *Group, apps []*App) (*App, error) {
+	if len(g.Members) == 0 {
+		return nil, fmt.Errorf("group %s: there should be meters", g.Name)
+	}
+
+	members := make([]*App, len(g.Members))
+	for i, m := range g.Members {
+		for _, a := range apps {
+			if a.Meter == m.Meter {
+				members[i] = a
+				break
+			}
+		}
+		if members[i] == nil {
+			return nil, fmt.Errorf("group %s: meter %s not found", g.Name, m.Meter)
+		}
+	}
+
+	first := members[0]
+
+	values := make([]map[time.Time]*Row, len(members))
+	var dates []time.Time
+	seen := make(map[time.Time]bool)
+
+	for i, a := range members {
+		if a.Month != first.Month || a.Year != first.Year {
+			return nil, fmt.Errorf("group %s: meter %s: all meters should have the same month", g.Name, a.Meter)
+		}
+
+		values[i] = make(map[time.Time]*Row)
+		for _, r := range a.Rows {
+			values[i][r.Date] = r
+			if !seen[r.Date] {
+				seen[r.Date] = true
+				dates = append(dates, r.Date)
+			}
+		}
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	// the hours missing at some meter make the combination meaningless
+	for i, a := range members {
+		var missing []time.Time
+		for _, date := range dates {
+			if _, ok := values[i][date]; !ok {
+				missing = append(missing, date)
+			}
+		}
+		if len(missing) != 0 {
+			return nil, fmt.Errorf("group %s: meter %s has no value of %d hours, the first is %s",
+				g.Name, a.Meter, len(missing), missing[0].Add(-time.Hour).Format("02.01.2006 15:04"))
+		}
+	}
+
+	var rows []*Row
+
+	for i, date := range dates {
+		row := &Row{ID: i + 1, Date: date}
+
+		for j, m := range g.Members {
+			r := values[j][date]
+			k := m.Sign * members[j].coefficient(r)
+
+			row.PPlus += r.PPlus * k
+			row.PMinus += r.PMinus * k
+			row.QPlus += r.QPlus * k
+			row.QMinus += r.QMinus * k
+		}
+
+		if row.PPlus < 0 {
+			return nil, fmt.Errorf("group %s: negative consumption %.2f kWh at %s", g.Name, row.PPlus, date.Add(-time.Hour).Format("02.01.2006 15:04"))
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("bad data: no rows found")
+	}
+
+	contract := g.Contract
+	if contract == "" {
+		contract = first.Contract
+	}
+
+	var sources []*Source
+	for _, m := range members {
+		sources = append(sources, m.Sources...)
+	}
+
+	a := newApp(&Profile{Meter: g.Name, Rows: rows, Sources: sources}, contract, first.CompanyName, g.Name, 1)
+	a.Schedule = first.Schedule
+	a.Calendar = first.Calendar
+	a.PeakHours = first.PeakHours
+	a.Export = first.Export
+
+	return a, nil
+}
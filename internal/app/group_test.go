@@ -0,0 +1,110 @@
+package app
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []*Group
+		wantErr bool
+	}{
+		{name: "empty", s: ""},
+		{
+			name: "net",
+			s:    "00000001=98765432:+23456789-12345678",
+			want: []*Group{{Name: "00000001", Contract: "98765432", Members: []*GroupMember{{Meter: "23456789", Sign: 1}, {Meter: "12345678", Sign: -1}}}},
+		},
+		{
+			name: "several groups without contract",
+			s:    "a=+1+2; b=-3+1",
+			want: []*Group{
+				{Name: "a", Members: []*GroupMember{{Meter: "1", Sign: 1}, {Meter: "2", Sign: 1}}},
+				{Name: "b", Members: []*GroupMember{{Meter: "3", Sign: -1}, {Meter: "1", Sign: 1}}},
+			},
+		},
+		{name: "without sign", s: "a=1-2", wantErr: true},
+		{name: "without serial", s: "a=+1-", wantErr: true},
+		{name: "without meters", s: "a=c:", wantErr: true},
+		{name: "without name", s: "=+1", wantErr: true},
+		{name: "path in name", s: "../../x=+1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGroups(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGroups() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGroups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGroup(t *testing.T) {
+	newMeter := func(meter string, coefficient float64, values []float64) *App {
+		return newApp(&Profile{Meter: meter, Rows: hourlyRows(values)}, "98765432", "OOO STAR", "", coefficient)
+	}
+
+	main := newMeter("1", 2, []float64{10, 10, 10})
+	sub := newMeter("2", 1, []float64{3, 4, 5})
+	short := newMeter("3", 1, []float64{1, 1})
+	big := newMeter("4", 1, []float64{30, 1, 1})
+
+	tests := []struct {
+		name    string
+		group   *Group
+		want    []float64
+		wantErr string
+	}{
+		{
+			name:  "main minus sub",
+			group: &Group{Name: "net", Members: []*GroupMember{{Meter: "1", Sign: 1}, {Meter: "2", Sign: -1}}},
+			want:  []float64{17, 16, 15},
+		},
+		{
+			name:    "mismatched coverage",
+			group:   &Group{Name: "net", Members: []*GroupMember{{Meter: "1", Sign: 1}, {Meter: "3", Sign: -1}}},
+			wantErr: "meter 3 has no value of 1 hours, the first is 01.02.2020 02:00",
+		},
+		{
+			name:    "negative consumption",
+			group:   &Group{Name: "net", Members: []*GroupMember{{Meter: "1", Sign: 1}, {Meter: "4", Sign: -1}}},
+			wantErr: "negative consumption",
+		},
+		{
+			name:    "unknown meter",
+			group:   &Group{Name: "net", Members: []*GroupMember{{Meter: "5", Sign: 1}}},
+			wantErr: "meter 5 not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewGroup(tt.group, []*App{main, sub, short, big})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewGroup() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewGroup() error = %v", err)
+			}
+
+			if got.Meter != "net" || got.Contract != "98765432" || got.Coefficient != 1 || got.Month != 2 {
+				t.Errorf("NewGroup() = %+v", got)
+			}
+			for i, r := range got.Rows {
+				if math.Abs(r.PPlus-tt.want[i]) > 1e-9 {
+					t.Errorf("NewGroup() row %d = %v, want %v", i, r.PPlus, tt.want[i])
+				}
+			}
+		})
+	}
+}
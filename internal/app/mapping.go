@@ -0,0 +1,40 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseMappings return mappings from a string like "serial=contract:coefficient,serial=contract:coefficient"
+func ParseMappings(s string) (map[string]Mapping, error) {
+	mappings := make(map[string]Mapping)
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("bad mapping %q: serial=contract:coefficient required", item)
+		}
+
+		values := strings.SplitN(kv[1], ":", 2)
+
+		m := Mapping{Contract: values[0], Coefficient: 1}
+
+		if len(values) == 2 {
+			coefficient, err := strconv.ParseFloat(values[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad mapping %q: %w", item, err)
+			}
+			m.Coefficient = coefficient
+		}
+
+		mappings[kv[0]] = m
+	}
+
+	return mappings, nil
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMappings(t *testing.T) {
+	type args struct {
+		s string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    map[string]Mapping
+		wantErr bool
+	}{
+		{
+			name: "empty string",
+			args: args{},
+			want: map[string]Mapping{},
+		},
+		{
+			name: "two meters",
+			args: args{
+				s: "23456789=98765432:4000, 12345678=11111111",
+			},
+			want: map[string]Mapping{
+				"23456789": {Contract: "98765432", Coefficient: 4000},
+				"12345678": {Contract: "11111111", Coefficient: 1},
+			},
+		},
+		{
+			name: "fail separator",
+			args: args{
+				s: "23456789",
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail coefficient",
+			args: args{
+				s: "23456789=98765432:fail",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMappings(tt.args.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseMappings() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMappings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
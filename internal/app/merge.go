@@ -0,0 +1,64 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Conflict rows of the same hour with different values
+type Conflict struct {
+	Date    time.Time
+	Kept    *Row
+	Dropped *Row
+}
+
+// String return a conflict description
+func (c *Conflict) String() string {
+	return fmt.Sprintf("%s: P+ %.4f kept, P+ %.4f dropped",
+		c.Date.Format("02.01.2006 15:04"), c.Kept.PPlus, c.Dropped.PPlus)
+}
+
+// merge return a profile with rows of all profiles sorted by date,
+// identical rows of the same hour are kept once,
+// rows of the same hour with different values are returned as conflicts and the earlier profile wins
+func merge(profiles []*Profile) (*Profile, []*Conflict, error) {
+	merged := &Profile{}
+	byDate := make(map[time.Time]*Row)
+
+	var conflicts []*Conflict
+
+	for _, p := range profiles {
+		if p.Meter != "" {
+			if merged.Meter != "" && merged.Meter != p.Meter {
+				return nil, nil, fmt.Errorf("bad data: exports of different meters %s and %s", merged.Meter, p.Meter)
+			}
+			merged.Meter = p.Meter
+		}
+		merged.Sources = append(merged.Sources, p.Sources...)
+
+		for _, r := range p.Rows {
+			kept, ok := byDate[r.Date]
+			if !ok {
+				byDate[r.Date] = r
+				merged.Rows = append(merged.Rows, r)
+				continue
+			}
+
+			if !sameValues(kept, r) {
+				conflicts = append(conflicts, &Conflict{Date: r.Date, Kept: kept, Dropped: r})
+			}
+		}
+	}
+
+	sort.SliceStable(merged.Rows, func(i, j int) bool {
+		return merged.Rows[i].Date.Before(merged.Rows[j].Date)
+	})
+
+	return merged, conflicts, nil
+}
+
+// sameValues return true if rows have the same readings
+func sameValues(a, b *Row) bool {
+	return a.PPlus == b.PPlus && a.PMinus == b.PMinus && a.QPlus == b.QPlus && a.QMinus == b.QMinus
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_merge(t *testing.T) {
+	first := &Row{ID: 2, PPlus: 0.0705, Date: time.Date(2020, 2, 1, 1, 0, 0, 0, time.UTC)}
+	second := &Row{ID: 4, PPlus: 0.0686, Date: time.Date(2020, 2, 1, 2, 0, 0, 0, time.UTC)}
+	third := &Row{ID: 6, PPlus: 0.0673, Date: time.Date(2020, 2, 1, 3, 0, 0, 0, time.UTC)}
+	changed := &Row{ID: 4, PPlus: 0.0001, Date: time.Date(2020, 2, 1, 2, 0, 0, 0, time.UTC)}
+
+	type args struct {
+		profiles []*Profile
+	}
+	tests := []struct {
+		name          string
+		args          args
+		want          *Profile
+		wantConflicts []*Conflict
+		wantErr       bool
+	}{
+		{
+			name: "identical overlap",
+			args: args{
+				profiles: []*Profile{
+					{Meter: "23456789", Rows: []*Row{second, third}},
+					{Meter: "23456789", Rows: []*Row{first, second}},
+				},
+			},
+			want: &Profile{Meter: "23456789", Rows: []*Row{first, second, third}},
+		},
+		{
+			name: "conflicting overlap",
+			args: args{
+				profiles: []*Profile{
+					{Meter: "23456789", Rows: []*Row{first, second}},
+					{Meter: "", Rows: []*Row{changed, third}},
+				},
+			},
+			want:          &Profile{Meter: "23456789", Rows: []*Row{first, second, third}},
+			wantConflicts: []*Conflict{{Date: second.Date, Kept: second, Dropped: changed}},
+		},
+		{
+			name: "different meters",
+			args: args{
+				profiles: []*Profile{
+					{Meter: "23456789", Rows: []*Row{first}},
+					{Meter: "12345678", Rows: []*Row{second}},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotConflicts, err := merge(tt.args.profiles)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("merge() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merge() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(gotConflicts, tt.wantConflicts) {
+				t.Errorf("merge() conflicts = %v, want %v", gotConflicts, tt.wantConflicts)
+			}
+		})
+	}
+}
+
+func TestNewMerged(t *testing.T) {
+	got, err := NewMerged([]string{"testdata/first_row.html", "testdata/23456789_feb.html"}, "98765432", "OOO STAR", "", 1)
+	if err != nil {
+		t.Fatalf("NewMerged() error = %v", err)
+	}
+
+	if len(got.Rows) != 696 {
+		t.Errorf("NewMerged() rows = %d, want 696", len(got.Rows))
+	}
+	if len(got.Conflicts) != 0 {
+		t.Errorf("NewMerged() conflicts = %v, want none", got.Conflicts)
+	}
+	if got.Meter != "23456789" {
+		t.Errorf("NewMerged() meter = %s, want 23456789", got.Meter)
+	}
+
+	if _, err := NewMerged(nil, "", "", "", 1); err == nil {
+		t.Errorf("NewMerged() without files should fail")
+	}
+}
@@ -0,0 +1,319 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Measuring channels of the 80020 accountpoint
+const (
+	channelPPlus  = "01"
+	channelPMinus = "02"
+	channelQPlus  = "03"
+	channelQMinus = "04"
+)
+
+// xmlMessage the 80020 message
+type xmlMessage struct {
+	Class    string `xml:"class,attr"`
+	Day      string `xml:"datetime>day"`
+	Sender   string `xml:"sender>name"`
+	Accounts []struct {
+		Code     string `xml:"code,attr"`
+		Channels []struct {
+			Code    string `xml:"code,attr"`
+			Periods []struct {
+				Start string `xml:"start,attr"`
+				End   string `xml:"end,attr"`
+				Value struct {
+					Status string `xml:"status,attr"`
+					Text   string `xml:",chardata"`
+				} `xml:"value"`
+			} `xml:"period"`
+		} `xml:"measuringchannel"`
+	} `xml:"area>accountpoint"`
+}
+
+// IsXML return true if all files are *.xml
+func IsXML(filenames []string) bool {
+	for _, filename := range filenames {
+		if !strings.EqualFold(filepath.Ext(filename), ".xml") {
+			return false
+		}
+	}
+
+	return len(filenames) != 0
+}
+
+// xmlFile the content of an 80020 file
+type xmlFile struct {
+	name string
+	data []byte
+}
+
+// ReadXML return a profile of each accountpoint of the 80020 files and the sender name,
+// the periods of an hour are added up and the profiles are sorted by the accountpoint code
+func ReadXML(filenames []string) ([]*Profile, string, error) {
+	var files []*xmlFile
+
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, "", err
+		}
+		files = append(files, &xmlFile{name: filename, data: data})
+	}
+
+	return readMessages(files)
+}
+
+// ReadZip return a profile of each accountpoint of the 80020 files of the *.zip archive and the sender name
+func ReadZip(filename string) ([]*Profile, string, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, "", err
+	}
+	defer zr.Close()
+
+	var files []*xmlFile
+
+	for _, f := range zr.File {
+		if !strings.EqualFold(path.Ext(f.Name), ".xml") {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %s: %w", filename, f.Name, err)
+		}
+
+		files = append(files, &xmlFile{name: f.Name, data: data})
+	}
+
+	if len(files) == 0 {
+		return nil, "", fmt.Errorf("%s: no *.xml files found", filename)
+	}
+
+	return readMessages(files)
+}
+
+// readMessages return a profile of each accountpoint of the 80020 files and the sender name
+func readMessages(files []*xmlFile) ([]*Profile, string, error) {
+	byCode := make(map[string]*Profile)
+	hours := make(map[string]map[time.Time]*Row)
+	var sender string
+
+	for _, file := range files {
+		filename, data := file.name, file.data
+
+		m, err := parseMessage(data)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", filename, err)
+		}
+		if sender == "" {
+			sender = m.sender
+		}
+
+		source := &Source{Filename: filepath.Base(filename), SHA256: fmt.Sprintf("%x", sha256.Sum256(data))}
+
+		for code, rows := range m.rows {
+			p, ok := byCode[code]
+			if !ok {
+				p = &Profile{Meter: code}
+				byCode[code] = p
+				hours[code] = make(map[time.Time]*Row)
+			}
+			p.Sources = append(p.Sources, source)
+
+			for _, r := range rows {
+				if _, ok := hours[code][r.Date]; ok {
+					return nil, "", fmt.Errorf("%s: accountpoint %s: hour ending %s is repeated", filename, code, r.Date.Format("02.01.2006 15:04"))
+				}
+				hours[code][r.Date] = r
+				p.Rows = append(p.Rows, r)
+			}
+		}
+	}
+
+	var profiles []*Profile
+	for _, p := range byCode {
+		if len(p.Rows) == 0 {
+			continue
+		}
+		sort.Slice(p.Rows, func(i, j int) bool { return p.Rows[i].Date.Before(p.Rows[j].Date) })
+		for i, r := range p.Rows {
+			r.ID = i + 1
+		}
+		profiles = append(profiles, p)
+	}
+
+	if len(profiles) == 0 {
+		return nil, "", errors.New("bad data: no rows found")
+	}
+
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Meter < profiles[j].Meter })
+
+	return profiles, sender, nil
+}
+
+// message the hourly rows of a single 80020 message by the accountpoint code
+type message struct {
+	sender string
+	rows   map[string][]*Row
+}
+
+// parseMessage return the hourly rows of the 80020 message,
+// a row dated hh:00 is the value of the hour ending at hh as in the meter export
+func parseMessage(data []byte) (*message, error) {
+	x := &xmlMessage{}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.CharsetReader = charsetReader
+
+	err := dec.Decode(x)
+	if err != nil {
+		return nil, err
+	}
+
+	if x.Class != "80020" {
+		return nil, fmt.Errorf("bad message: class 80020 required, got %q", x.Class)
+	}
+
+	day, err := time.Parse("20060102", x.Day)
+	if err != nil {
+		return nil, fmt.Errorf("bad message: %w", err)
+	}
+
+	m := &message{sender: x.Sender, rows: make(map[string][]*Row)}
+
+	for _, a := range x.Accounts {
+		byHour := make(map[int]*Row)
+
+		for _, c := range a.Channels {
+			// the other channels would add hours without values
+			switch c.Code {
+			case channelPPlus, channelPMinus, channelQPlus, channelQMinus:
+			default:
+				continue
+			}
+
+			for _, p := range c.Periods {
+				start, err := periodTime(p.Start)
+				if err != nil {
+					return nil, fmt.Errorf("accountpoint %s: %w", a.Code, err)
+				}
+
+				v, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(p.Value.Text), ",", ".", 1), 64)
+				if err != nil {
+					return nil, fmt.Errorf("accountpoint %s: bad value %q", a.Code, p.Value.Text)
+				}
+
+				hour := start / 60
+				r, ok := byHour[hour]
+				if !ok {
+					r = &Row{Date: day.Add(time.Duration(hour+1) * time.Hour)}
+					byHour[hour] = r
+				}
+
+				switch c.Code {
+				case channelPPlus:
+					r.PPlus += v
+				case channelPMinus:
+					r.PMinus += v
+				case channelQPlus:
+					r.QPlus += v
+				case channelQMinus:
+					r.QMinus += v
+				}
+
+				if s := strings.TrimSpace(p.Value.Status); s != "" && s != "0" {
+					r.Note = "status " + s
+				}
+			}
+		}
+
+		var hoursOfDay []int
+		for hour := range byHour {
+			hoursOfDay = append(hoursOfDay, hour)
+		}
+		sort.Ints(hoursOfDay)
+
+		for _, hour := range hoursOfDay {
+			m.rows[a.Code] = append(m.rows[a.Code], byHour[hour])
+		}
+	}
+
+	return m, nil
+}
+
+// periodTime return the minutes of the day of the period start HHMM
+func periodTime(s string) (int, error) {
+	if len(s) != 4 {
+		return 0, fmt.Errorf("bad period %q", s)
+	}
+
+	hh, err := strconv.Atoi(s[:2])
+	if err != nil || hh > 23 {
+		return 0, fmt.Errorf("bad period %q", s)
+	}
+
+	mm, err := strconv.Atoi(s[2:])
+	if err != nil || mm > 59 {
+		return 0, fmt.Errorf("bad period %q", s)
+	}
+
+	return hh*60 + mm, nil
+}
+
+// charsetReader decodes windows-1251 used by the 80020 messages
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "windows-1251", "cp1251":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(decodeWindows1251(data)), nil
+	case "utf-8", "":
+		return input, nil
+	}
+
+	return nil, fmt.Errorf("unsupported charset %s", charset)
+}
+
+// windows1251 the characters of the bytes from 0x80 to 0xBF, the bytes from 0xC0 are А-я
+var windows1251 = []rune("ЂЃ‚ѓ„…†‡€‰Љ‹ЊЌЋЏђ‘’“”•–—\ufffd™љ›њќћџ\u00a0ЎўЈ¤Ґ¦§Ё©Є«¬\u00ad®Ї°±Ііґµ¶·ё№є»јЅѕї")
+
+// decodeWindows1251 return the UTF-8 text of windows-1251 bytes
+func decodeWindows1251(data []byte) string {
+	var b strings.Builder
+	for _, c := range data {
+		switch {
+		case c < 0x80:
+			b.WriteByte(c)
+		case c < 0xC0:
+			b.WriteRune(windows1251[c-0x80])
+		default:
+			b.WriteRune(rune('А' + int(c) - 0xC0))
+		}
+	}
+
+	return b.String()
+}
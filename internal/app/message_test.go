@@ -0,0 +1,121 @@
+package app
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadXML(t *testing.T) {
+	profiles, sender, err := ReadXML([]string{"testdata/80020_halfhour.xml"})
+	if err != nil {
+		t.Fatalf("ReadXML() error = %v", err)
+	}
+
+	if sender != "ООО Сбыт" {
+		t.Errorf("ReadXML() sender = %q", sender)
+	}
+	if len(profiles) != 2 || profiles[0].Meter != "11111111000001" || profiles[1].Meter != "11111111000002" {
+		t.Fatalf("ReadXML() = %v", profiles)
+	}
+
+	want := []*Row{
+		{ID: 1, PPlus: 4, PMinus: 0.1, QPlus: 0.5, Date: time.Date(2020, 3, 2, 1, 0, 0, 0, time.UTC)},
+		{ID: 2, PPlus: 4, QPlus: 0.25, Note: "status 1", Date: time.Date(2020, 3, 2, 2, 0, 0, 0, time.UTC)},
+	}
+	if !reflect.DeepEqual(profiles[0].Rows, want) {
+		t.Errorf("ReadXML() rows = %+v %+v, want %+v %+v", profiles[0].Rows[0], profiles[0].Rows[1], want[0], want[1])
+	}
+
+	// the channel 05 adds no hour
+	if len(profiles[1].Rows) != 1 {
+		t.Fatalf("ReadXML() rows of the second accountpoint = %d, want 1", len(profiles[1].Rows))
+	}
+	if last := profiles[1].Rows[0]; last.PPlus != 7 || !last.Date.Equal(time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ReadXML() the last hour of the day = %+v", last)
+	}
+
+	if _, _, err := ReadXML([]string{"testdata/80020_halfhour.xml", "testdata/80020_halfhour.xml"}); err == nil {
+		t.Errorf("ReadXML() of the repeated hours should fail")
+	}
+	if _, _, err := ReadXML([]string{"testdata/first_row.html"}); err == nil {
+		t.Errorf("ReadXML() of a meter export should fail")
+	}
+}
+
+func TestReadXML_roundTrip(t *testing.T) {
+	dirName := t.TempDir()
+
+	var filenames []string
+	var want []float64
+
+	// the files keep a digit after the decimal comma, so the values are multiples of 0.1
+	// to be read back exactly
+	for day := 1; day <= 2; day++ {
+		values := make([]float64, 24)
+		for h := range values {
+			values[h] = float64(day*100+h) / 10
+		}
+		want = append(want, values...)
+
+		head := newHead(time.Date(2020, 2, day, 0, 0, 0, 0, time.UTC), "98765432", "OOO STAR", "23456789")
+		body, err := newBody(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buff, err := toBuffer(head, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := toFile(buff, dirName, head); err != nil {
+			t.Fatal(err)
+		}
+		filenames = append(filenames, path.Join(dirName, fileName(head)))
+	}
+
+	// the values are already scaled, the coefficients are ignored
+	apps, err := NewXML(filenames, "", Mapping{Contract: "98765432", Coefficient: 40}, nil)
+	if err != nil {
+		t.Fatalf("NewXML() error = %v", err)
+	}
+	if len(apps) != 1 {
+		t.Fatalf("NewXML() len = %d, want 1", len(apps))
+	}
+
+	a := apps[0]
+	a.Coefficients = []*CoefficientPeriod{{CT: 200, VT: 1, From: time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)}}
+	if a.Meter != "23456789" || a.Contract != "98765432" || a.CompanyName != "OOO STAR" || a.Month != 2 || a.FirstHour != 1 {
+		t.Errorf("NewXML() = %s %s %s %d %d", a.Meter, a.Contract, a.CompanyName, a.Month, a.FirstHour)
+	}
+	if len(a.Rows) != len(want) {
+		t.Fatalf("NewXML() rows = %d, want %d", len(a.Rows), len(want))
+	}
+	for i, r := range a.Rows {
+		if r.PPlus != want[i] || !r.Date.Equal(time.Date(2020, 2, 1, i+1, 0, 0, 0, time.UTC)) {
+			t.Errorf("NewXML() row %d = %v at %v, want %v", i, r.PPlus, r.Date, want[i])
+		}
+	}
+
+	// the files written again are the same
+	days := a.daily()
+	for day := 1; day <= 2; day++ {
+		head := newHead(time.Date(2020, 2, day, 0, 0, 0, 0, time.UTC), a.Contract, a.CompanyName, a.Meter)
+		body, err := newBody(days[day-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		buff, err := toBuffer(head, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		original, err := os.ReadFile(filenames[day-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buff.String() != string(original) {
+			t.Errorf("day %d differs after the round trip", day)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Tariff prices of a single month, energy prices are in rubles per MWh,
+// capacity and transmission maintenance prices are in rubles per MW a month
+type Tariff struct {
+	SingleRate              float64              `json:"single_rate"`
+	Schedule                *Schedule            `json:"schedule"`
+	ZonePrices              map[string]float64   `json:"zone_prices"`
+	Hourly                  map[string][]float64 `json:"hourly"`   // unregulated prices by date 02.01.2006
+	Capacity                float64              `json:"capacity"` // capacity price
+	PeakHours               PeakHours            `json:"peak_hours"`
+	NetworkPeak             [][2]int             `json:"network_peak"`
+	Transmission            float64              `json:"transmission"` // single-rate transmission
+	TransmissionMaintenance float64              `json:"transmission_maintenance"`
+	TransmissionLosses      float64              `json:"transmission_losses"`
+}
+
+// Cost monthly cost of a price category in rubles
+type Cost struct {
+	Category     int
+	Energy       float64
+	Capacity     float64
+	Transmission float64
+	Total        float64
+	Note         string
+	Err          error
+}
+
+// LoadTariff reads the *.json tariff file
+func LoadTariff(filename string) (*Tariff, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tariff{}
+
+	err = json.Unmarshal(data, t)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Schedule != nil {
+		err = t.Schedule.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// Costs return the cost of every price category from 1 to 6,
+// the category without the required prices has Err
+func (a *App) Costs(t *Tariff) []*Cost {
+	var costs []*Cost
+	for category := 1; category <= 6; category++ {
+		c, err := a.Cost(category, t)
+		if err != nil {
+			c = &Cost{Category: category, Err: err}
+		}
+		costs = append(costs, c)
+	}
+
+	return costs
+}
+
+// Cheapest return the cheapest category without errors, nil if there is no one
+func Cheapest(costs []*Cost) *Cost {
+	var cheapest *Cost
+	for _, c := range costs {
+		if c.Err == nil && (cheapest == nil || c.Total < cheapest.Total) {
+			cheapest = c
+		}
+	}
+
+	return cheapest
+}
+
+// Cost return the monthly cost of the price category
+func (a *App) Cost(category int, t *Tariff) (*Cost, error) {
+	c := &Cost{Category: category}
+
+	volume := a.volume()
+
+	switch category {
+	case 1:
+		if t.SingleRate == 0 {
+			return nil, errors.New("category 1: single rate price required")
+		}
+		c.Energy = volume * t.SingleRate
+	case 2:
+		if t.Schedule == nil || len(t.ZonePrices) == 0 {
+			return nil, errors.New("category 2: schedule and zone prices required")
+		}
+		for _, z := range a.zoneVolumes(t.Schedule) {
+			price, ok := t.ZonePrices[z.Name]
+			if !ok {
+				return nil, fmt.Errorf("category 2: price of zone %s required", z.Name)
+			}
+			c.Energy += z.Total / 1000 * price
+		}
+	case 3, 4, 5, 6:
+		energy, err := a.hourlyEnergy(t)
+		if err != nil {
+			return nil, fmt.Errorf("category %d: %w", category, err)
+		}
+		c.Energy = energy
+
+		capacity, err := a.peakCapacity(a.tariffPeakHours(t))
+		if err != nil {
+			return nil, fmt.Errorf("category %d: %w", category, err)
+		}
+		c.Capacity = capacity * t.Capacity
+	default:
+		return nil, fmt.Errorf("bad price category %d", category)
+	}
+
+	switch category {
+	case 1, 2, 3, 5:
+		c.Transmission = volume * t.Transmission
+	case 4, 6:
+		network, err := a.networkCapacity(t)
+		if err != nil {
+			return nil, fmt.Errorf("category %d: %w", category, err)
+		}
+		c.Transmission = network*t.TransmissionMaintenance + volume*t.TransmissionLosses
+	}
+
+	if category == 5 || category == 6 {
+		c.Note = "deviations from the planned volumes are not included"
+	}
+
+	c.Total = c.Energy + c.Capacity + c.Transmission
+
+	return c, nil
+}
+
+// tariffPeakHours return the planned peak hours of the tariff, otherwise the peak hours of App
+func (a *App) tariffPeakHours(t *Tariff) PeakHours {
+	if len(t.PeakHours) != 0 {
+		return t.PeakHours
+	}
+
+	return a.PeakHours
+}
+
+// hourValue the scaled consumption of a single hour
+type hourValue struct {
+	Start time.Time
+	Value float64
+}
+
+// hours return the scaled consumption of each hour of the month
+func (a *App) hours() []hourValue {
+	var hours []hourValue
+	for _, r := range a.Rows {
+		start := r.Date.Add(-time.Hour)
+		if start.Year() == a.Year && int(start.Month()) == a.Month {
+			hours = append(hours, hourValue{Start: start, Value: r.PPlus * a.coefficient(r)})
+		}
+	}
+
+	return hours
+}
+
+// volume return the scaled consumption in MWh
+func (a *App) volume() float64 {
+	var sum float64
+	for _, h := range a.hours() {
+		sum += h.Value
+	}
+
+	return sum / 1000
+}
+
+// zoneVolumes return the scaled consumption of each zone in kWh
+func (a *App) zoneVolumes(s *Schedule) []*ZoneTotal {
+	totals := s.newZoneTotals(time.Month(a.Month))
+	for _, h := range a.hours() {
+		totals = addZone(totals, s.zone(h.Start), h.Value)
+	}
+
+	return totals
+}
+
+// hourlyEnergy return the cost of energy at the unregulated hourly prices
+func (a *App) hourlyEnergy(t *Tariff) (float64, error) {
+	var sum float64
+
+	for _, h := range a.hours() {
+		prices, ok := t.Hourly[h.Start.Format("02.01.2006")]
+		if !ok || len(prices) != 24 {
+			return 0, fmt.Errorf("24 hourly prices of %s required", h.Start.Format("02.01.2006"))
+		}
+		sum += h.Value / 1000 * prices[h.Start.Hour()]
+	}
+
+	return sum, nil
+}
+
+// networkCapacity return the average of the daily maximum in MW during the network peak hours of the working days
+func (a *App) networkCapacity(t *Tariff) (float64, error) {
+	peaks := a.tariffPeakHours(t)
+	if len(t.NetworkPeak) == 0 || len(peaks) == 0 {
+		return 0, errors.New("network peak hours and planned peak hours required")
+	}
+
+	var dates []string
+	days := make(map[string]float64)
+
+	for _, h := range a.hours() {
+		date := h.Start.Format("02.01.2006")
+		if !a.isPeakDay(peaks, h.Start) { // the peak hours are planned for the working days only
+			continue
+		}
+
+		for _, r := range t.NetworkPeak {
+			if h.Start.Hour() >= r[0] && h.Start.Hour() < r[1] {
+				if _, ok := days[date]; !ok {
+					dates = append(dates, date)
+				}
+				if h.Value >= days[date] {
+					days[date] = h.Value
+				}
+			}
+		}
+	}
+
+	if len(dates) == 0 {
+		return 0, errors.New("no consumption during the network peak hours")
+	}
+
+	var sum float64
+	for _, date := range dates {
+		sum += days[date]
+	}
+
+	return sum / float64(len(dates)) / 1000, nil
+}
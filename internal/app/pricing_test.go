@@ -0,0 +1,105 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestApp_Cost(t *testing.T) {
+	// two days of 1 MWh every hour, 01.02.2020 is saturday and 03.02.2020 is monday
+	rows := hourlyRows(make([]float64, 72))
+	for i, r := range rows {
+		r.PPlus = 1
+		if i == 24*2+10 {
+			r.PPlus = 3 // the planned peak hour of monday
+		}
+	}
+	a := &App{Coefficient: 1000, Rows: rows, Month: 2, Year: 2020}
+
+	hourly := make([]float64, 24)
+	for i := range hourly {
+		hourly[i] = 2000
+	}
+
+	tariff := &Tariff{
+		SingleRate: 4000,
+		Schedule:   DayNightSchedule(),
+		ZonePrices: map[string]float64{"day": 5000, "night": 2000},
+		Hourly: map[string][]float64{
+			"01.02.2020": hourly,
+			"02.02.2020": hourly,
+			"03.02.2020": hourly,
+		},
+		Capacity:                800000,
+		PeakHours:               map[string]int{"03.02.2020": 10},
+		NetworkPeak:             [][2]int{{8, 11}},
+		Transmission:            1000,
+		TransmissionMaintenance: 1000000,
+		TransmissionLosses:      100,
+	}
+
+	tests := []struct {
+		category int
+		want     Cost
+		wantErr  bool
+	}{
+		{category: 1, want: Cost{Energy: 74 * 4000, Transmission: 74 * 1000}},
+		{category: 2, want: Cost{Energy: (16*3+2)*5000 + 24*2000, Transmission: 74 * 1000}},
+		{category: 3, want: Cost{Energy: 74 * 2000, Capacity: 3 * 800000, Transmission: 74 * 1000}},
+		{category: 4, want: Cost{Energy: 74 * 2000, Capacity: 3 * 800000, Transmission: 3*1000000 + 74*100}},
+		{category: 7, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("category %d", tt.category), func(t *testing.T) {
+			got, err := a.Cost(tt.category, tariff)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Cost() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			total := tt.want.Energy + tt.want.Capacity + tt.want.Transmission
+			if math.Abs(got.Energy-tt.want.Energy) > 1e-6 ||
+				math.Abs(got.Capacity-tt.want.Capacity) > 1e-6 ||
+				math.Abs(got.Transmission-tt.want.Transmission) > 1e-6 ||
+				math.Abs(got.Total-total) > 1e-6 {
+				t.Errorf("Cost() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	delete(tariff.Hourly, "02.02.2020")
+	if _, err := a.Cost(3, tariff); err == nil {
+		t.Errorf("Cost() without hourly prices of a day should fail")
+	}
+}
+
+func TestApp_Costs(t *testing.T) {
+	tariff, err := LoadTariff("testdata/tariff_feb.json")
+	if err != nil {
+		t.Fatalf("LoadTariff() error = %v", err)
+	}
+
+	a, err := New("testdata/23456789_feb.html", "98765432", "OOO STAR", "", 4000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	costs := a.Costs(tariff)
+	if len(costs) != 6 {
+		t.Fatalf("Costs() len = %d, want 6", len(costs))
+	}
+	for _, c := range costs {
+		if c.Err != nil || c.Total <= 0 {
+			t.Errorf("Costs() category %d = %+v", c.Category, c)
+		}
+	}
+
+	if Cheapest(costs) == nil {
+		t.Errorf("Cheapest() = nil")
+	}
+	if Cheapest(nil) != nil {
+		t.Errorf("Cheapest() of nothing should be nil")
+	}
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+)
+
+// PowerFactor limit of tan φ during the peak window
+type PowerFactor struct {
+	PeakStart int     // first hour of the peak window
+	PeakEnd   int     // hour when the peak window ends
+	Limit     float64 // the highest allowed tan φ
+}
+
+// DefaultPowerFactor return the limit of a 0.4 kV connection
+func DefaultPowerFactor() PowerFactor {
+	return PowerFactor{PeakStart: 7, PeakEnd: 23, Limit: 0.35}
+}
+
+// TanPhi tan φ of a single hour
+type TanPhi struct {
+	Date      time.Time // the hour start
+	P         float64
+	Q         float64
+	Value     float64
+	Peak      bool
+	Violation bool
+}
+
+// TanPhiDay violations of a single day
+type TanPhiDay struct {
+	Date  time.Time
+	Hours int
+	Max   float64
+}
+
+// TanPhiReport hourly and monthly tan φ
+type TanPhiReport struct {
+	Limit PowerFactor
+	Hours []*TanPhi
+	Month float64 // all hours of the month
+	Peak  float64 // peak hours of the month
+	Days  []*TanPhiDay
+}
+
+// TanPhi return tan φ of the scaled hourly values
+func (a *App) TanPhi(pf PowerFactor) *TanPhiReport {
+	report := &TanPhiReport{Limit: pf}
+
+	var p, q, peakP, peakQ float64
+
+	for _, r := range a.Rows {
+		k := a.coefficient(r)
+		h := &TanPhi{
+			Date: r.Date.Add(-time.Hour),
+			P:    r.PPlus * k,
+			Q:    r.QPlus * k,
+		}
+		h.Peak = h.Date.Hour() >= pf.PeakStart && h.Date.Hour() < pf.PeakEnd
+
+		// the hour without active energy has no meaningful tan φ
+		if h.P != 0 {
+			h.Value = h.Q / h.P
+			h.Violation = h.Peak && h.Value > pf.Limit
+		}
+
+		p += h.P
+		q += h.Q
+		if h.Peak {
+			peakP += h.P
+			peakQ += h.Q
+		}
+
+		if h.Violation {
+			day := time.Date(h.Date.Year(), h.Date.Month(), h.Date.Day(), 0, 0, 0, 0, time.UTC)
+			if n := len(report.Days); n == 0 || !report.Days[n-1].Date.Equal(day) {
+				report.Days = append(report.Days, &TanPhiDay{Date: day})
+			}
+			d := report.Days[len(report.Days)-1]
+			d.Hours++
+			if h.Value > d.Max {
+				d.Max = h.Value
+			}
+		}
+
+		report.Hours = append(report.Hours, h)
+	}
+
+	if p != 0 {
+		report.Month = q / p
+	}
+	if peakP != 0 {
+		report.Peak = peakQ / peakP
+	}
+
+	return report
+}
+
+// Violations return the number of hours above the limit
+func (r *TanPhiReport) Violations() int {
+	var n int
+	for _, d := range r.Days {
+		n += d.Hours
+	}
+
+	return n
+}
+
+// WriteCSV writes hourly tan φ separated by semicolons
+func (r *TanPhiReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+
+	err := cw.Write([]string{"date", "hour", "p_plus", "q_plus", "tan_phi", "peak", "violation"})
+	if err != nil {
+		return err
+	}
+
+	for _, h := range r.Hours {
+		err = cw.Write([]string{
+			h.Date.Format("02.01.2006"),
+			fmt.Sprintf("%02d", h.Date.Hour()),
+			decimal(h.P, 2),
+			decimal(h.Q, 2),
+			decimal(h.Value, 3),
+			yesNo(h.Peak),
+			yesNo(h.Violation),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// yesNo return 1 if b is true, otherwise 0
+func yesNo(b bool) string {
+	if b {
+		return "1"
+	}
+
+	return "0"
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestApp_TanPhi(t *testing.T) {
+	rows := hourlyRows(make([]float64, 48))
+	for i, r := range rows {
+		r.PPlus = 1
+		r.QPlus = 0.2
+		if i == 10 || i == 11 || i == 34 {
+			r.QPlus = 0.5 // peak hours of both days
+		}
+		if i == 2 {
+			r.QPlus = 0.9 // night hour
+		}
+	}
+
+	a := &App{Coefficient: 10, Rows: rows}
+
+	got := a.TanPhi(PowerFactor{PeakStart: 7, PeakEnd: 23, Limit: 0.35})
+
+	if len(got.Hours) != 48 {
+		t.Fatalf("TanPhi() hours = %d, want 48", len(got.Hours))
+	}
+	if got.Hours[10].P != 10 || got.Hours[10].Q != 5 || got.Hours[10].Value != 0.5 {
+		t.Errorf("TanPhi() hour = %+v, want scaled values", got.Hours[10])
+	}
+	if got.Violations() != 3 {
+		t.Errorf("TanPhi() violations = %d, want 3", got.Violations())
+	}
+	if len(got.Days) != 2 || got.Days[0].Hours != 2 || !got.Days[1].Date.Equal(time.Date(2020, 2, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("TanPhi() days = %v", got.Days)
+	}
+
+	wantMonth := (0.2*44 + 0.5*3 + 0.9) / 48
+	if math.Abs(got.Month-wantMonth) > 1e-9 {
+		t.Errorf("TanPhi() month = %v, want %v", got.Month, wantMonth)
+	}
+
+	wantPeak := (0.2*29 + 0.5*3) / 32
+	if math.Abs(got.Peak-wantPeak) > 1e-9 {
+		t.Errorf("TanPhi() peak = %v, want %v", got.Peak, wantPeak)
+	}
+}
+
+func TestTanPhiReport_WriteCSV(t *testing.T) {
+	a := &App{Coefficient: 1, Rows: hourlyRows([]float64{2})}
+
+	buff := new(bytes.Buffer)
+	if err := a.TanPhi(DefaultPowerFactor()).WriteCSV(buff); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	want := "date;hour;p_plus;q_plus;tan_phi;peak;violation\n01.02.2020;00;2,00;1,00;0,500;0;0\n"
+	if buff.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", buff.String(), want)
+	}
+}
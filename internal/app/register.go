@@ -0,0 +1,155 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Register cumulative active energy readings of the meter at the start and the end of the month
+type Register struct {
+	Start float64
+	End   float64
+}
+
+// Registers register readings by meter serial number
+type Registers map[string]Register
+
+// Reconciliation the profile total against the register readings
+type Reconciliation struct {
+	Register  Register
+	Expected  float64 // the scaled register delta in kWh
+	Total     float64 // the scaled profile total in kWh
+	Diff      float64 // the profile total minus the register delta
+	Percent   float64 // Diff against the register delta
+	Tolerance float64 // the allowed Percent
+	Corrected bool    // the hourly values are scaled to the register delta
+}
+
+// LoadRegisters reads the register readings from the *.csv file with rows "meter;start;end",
+// decimal commas are allowed
+func LoadRegisters(filename string) (Registers, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	registers := make(Registers)
+
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		row := strings.TrimSpace(string(line))
+		if row == "" || strings.HasPrefix(row, "meter") { // skips the header
+			continue
+		}
+
+		fields := strings.Split(row, ";")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: meter;start;end required", filename, i+1)
+		}
+
+		r, err := ParseRegister(fields[1], fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, i+1, err)
+		}
+
+		registers[strings.TrimSpace(fields[0])] = r
+	}
+
+	if len(registers) == 0 {
+		return nil, fmt.Errorf("%s: no register readings found", filename)
+	}
+
+	return registers, nil
+}
+
+// ParseRegister return the register of the start and end readings, decimal commas are allowed
+func ParseRegister(start, end string) (Register, error) {
+	s, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(start), ",", ".", 1), 64)
+	if err != nil {
+		return Register{}, fmt.Errorf("bad start reading %q", start)
+	}
+
+	e, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(end), ",", ".", 1), 64)
+	if err != nil {
+		return Register{}, fmt.Errorf("bad end reading %q", end)
+	}
+
+	if e < s {
+		return Register{}, fmt.Errorf("end reading %v is less than start reading %v", e, s)
+	}
+
+	return Register{Start: s, End: e}, nil
+}
+
+// Reconcile compares the profile total of the month with the scaled register delta
+// and sets Reconciliation, an earlier correction is dropped, tolerance is in percent
+func (a *App) Reconcile(r Register, tolerance float64) (*Reconciliation, error) {
+	if r.End < r.Start {
+		return nil, fmt.Errorf("meter %s: end reading is less than start reading", a.Meter)
+	}
+
+	// the register delta can't be scaled by several coefficients without the readings of the change
+	usage := a.CoefficientUsage()
+	if len(usage) > 1 {
+		return nil, fmt.Errorf("meter %s: the coefficient changes within the month", a.Meter)
+	}
+
+	coefficient := a.Coefficient
+	if len(usage) == 1 {
+		coefficient = usage[0].Coefficient
+	}
+
+	rec := &Reconciliation{Register: r, Expected: (r.End - r.Start) * coefficient, Tolerance: tolerance}
+
+	a.Correction = 0
+	for _, h := range a.hours() {
+		rec.Total += h.Value
+	}
+
+	rec.Diff = rec.Total - rec.Expected
+	if rec.Expected != 0 {
+		rec.Percent = rec.Diff / rec.Expected * 100
+	} else if rec.Total != 0 {
+		rec.Percent = 100
+	}
+
+	a.Reconciliation = rec
+
+	return rec, nil
+}
+
+// OK return true if the discrepancy is within the tolerance
+func (rec *Reconciliation) OK() bool {
+	return math.Abs(rec.Percent) <= rec.Tolerance
+}
+
+// String return the reconciliation as text
+func (rec *Reconciliation) String() string {
+	status := "ok"
+	if !rec.OK() {
+		status = "discrepancy"
+	}
+
+	s := fmt.Sprintf("register %.2f kWh, profile %.2f kWh, diff %.2f kWh (%.2f%%): %s", rec.Expected, rec.Total, rec.Diff, rec.Percent, status)
+	if rec.Corrected {
+		s += ", corrected"
+	}
+
+	return s
+}
+
+// Correct sets Correction so that the scaled values of all the channels of the month are proportional
+// and their P+ total matches the register delta, the rows are kept as read
+func (a *App) Correct(rec *Reconciliation) error {
+	if rec.Total == 0 {
+		return fmt.Errorf("meter %s: profile without consumption can't be corrected", a.Meter)
+	}
+
+	a.Correction = rec.Expected / rec.Total
+	rec.Corrected = true
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLoadRegisters(t *testing.T) {
+	got, err := LoadRegisters("testdata/registers_feb.csv")
+	if err != nil {
+		t.Fatalf("LoadRegisters() error = %v", err)
+	}
+
+	if len(got) != 2 || got["23456789"] != (Register{Start: 1200.5, End: 1251.7}) {
+		t.Errorf("LoadRegisters() = %v", got)
+	}
+
+	if _, err := LoadRegisters("testdata/peak_hours_feb.csv"); err == nil {
+		t.Errorf("LoadRegisters() of a bad file should fail")
+	}
+}
+
+func TestParseRegister(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end string
+		want       Register
+		wantErr    bool
+	}{
+		{name: "decimal comma", start: "10,5", end: "20", want: Register{Start: 10.5, End: 20}},
+		{name: "decimal point", start: " 10.5", end: "20.25 ", want: Register{Start: 10.5, End: 20.25}},
+		{name: "end before start", start: "20", end: "10", wantErr: true},
+		{name: "not a number", start: "x", end: "10", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRegister(tt.start, tt.end)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRegister() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRegister() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_Reconcile(t *testing.T) {
+	tests := []struct {
+		name      string
+		register  Register
+		tolerance float64
+		wantDiff  float64
+		wantOK    bool
+	}{
+		{name: "match", register: Register{Start: 100, End: 124}, tolerance: 0.5, wantDiff: 0, wantOK: true},
+		{name: "within tolerance", register: Register{Start: 100, End: 124.1}, tolerance: 0.5, wantDiff: -0.2, wantOK: true},
+		{name: "discrepancy", register: Register{Start: 100, End: 130}, tolerance: 0.5, wantDiff: -12, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 24 hours of 1 kWh and the coefficient 2
+			a := &App{Coefficient: 2, Month: 2, Year: 2020, Rows: hourlyRows(make([]float64, 24))}
+			for _, r := range a.Rows {
+				r.PPlus = 1
+			}
+
+			got, err := a.Reconcile(tt.register, tt.tolerance)
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			if math.Abs(got.Diff-tt.wantDiff) > 1e-9 || got.OK() != tt.wantOK {
+				t.Errorf("Reconcile() = %v, want diff %v ok %v", got, tt.wantDiff, tt.wantOK)
+			}
+			if a.Reconciliation != got {
+				t.Errorf("Reconcile() should set Reconciliation")
+			}
+
+			err = a.Correct(got)
+			if err != nil {
+				t.Fatalf("Correct() error = %v", err)
+			}
+			if math.Abs(a.volume()*1000-got.Expected) > 1e-9 || a.Rows[0].PPlus != 1 {
+				t.Errorf("Correct() total %v, want %v, rows %v", a.volume()*1000, got.Expected, a.Rows[0].PPlus)
+			}
+
+			// the reconciliation is repeated against the rows as read
+			again, _ := a.Reconcile(tt.register, tt.tolerance)
+			if math.Abs(again.Diff-tt.wantDiff) > 1e-9 || a.Correction != 0 {
+				t.Errorf("Reconcile() after Correct() = %v", again)
+			}
+		})
+	}
+
+	a := &App{Coefficient: 1, Month: 2, Year: 2020, Rows: hourlyRows(make([]float64, 24))}
+	rec, _ := a.Reconcile(Register{Start: 0, End: 10}, 1)
+	if err := a.Correct(rec); err == nil {
+		t.Errorf("Correct() of a profile without consumption should fail")
+	}
+}
+
+func TestApp_Correct_tanPhi(t *testing.T) {
+	a := &App{Coefficient: 2, Month: 2, Year: 2020, Rows: hourlyRows(make([]float64, 24))}
+	for _, r := range a.Rows {
+		r.PPlus = 1
+		r.QPlus = 0.5
+	}
+
+	rec, err := a.Reconcile(Register{Start: 0, End: 36}, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Correct(rec); err != nil {
+		t.Fatalf("Correct() error = %v", err)
+	}
+
+	// P+ and Q+ are scaled together, tan φ stays the same
+	got := a.TanPhi(DefaultPowerFactor())
+	h := got.Hours[0]
+	if math.Abs(h.P-3) > 1e-9 || math.Abs(h.Q-1.5) > 1e-9 || math.Abs(got.Month-0.5) > 1e-9 {
+		t.Errorf("TanPhi() after Correct() = %v %v, month %v", h.P, h.Q, got.Month)
+	}
+}
@@ -0,0 +1,263 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Registry the settings of each client meter by its serial number
+type Registry map[string]*Client
+
+// Client the settings of a single meter
+type Client struct {
+	Contract     string       `json:"contract"`
+	CompanyName  string       `json:"company_name"`
+	INN          string       `json:"inn,omitempty"`
+	Coefficient  float64      `json:"coefficient"`
+	Coefficients []*RatioSpec `json:"coefficients,omitempty"`
+	Recipient    *Recipient   `json:"recipient,omitempty"`
+
+	periods []*CoefficientPeriod
+}
+
+// RatioSpec the transformer ratios of a period in the registry, dates are "02.01.2006 15:04"
+// and ratios are numbers or like "200/5"
+type RatioSpec struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	CT   string `json:"ct"`
+	VT   string `json:"vt"`
+}
+
+// Recipient the energy company receiving the 80020 files
+type Recipient struct {
+	Name     string `json:"name"`      // Batch writes the files of each recipient into their own directory
+	AreaINN  string `json:"area_inn"`  // INN of the area of the 80020 files
+	AreaName string `json:"area_name"` // name of the area of the 80020 files
+}
+
+// BatchResult the outcome of converting a single file of the batch
+type BatchResult struct {
+	Filename string
+	Dir      string // the directory of the month files
+	Meters   []string
+	Total    float64
+	Err      error
+}
+
+// LoadRegistry reads the registry from the *.json file
+func LoadRegistry(filename string) (Registry, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var r Registry
+	err = json.Unmarshal(data, &r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	if len(r) == 0 {
+		return nil, fmt.Errorf("%s: no meters found", filename)
+	}
+
+	for meter, c := range r {
+		if c == nil || c.Contract == "" {
+			return nil, fmt.Errorf("%s: meter %s: contract required", filename, meter)
+		}
+		if c.Coefficient == 0 {
+			c.Coefficient = 1
+		}
+
+		for _, spec := range c.Coefficients {
+			p, err := newCoefficientPeriod(spec.From, spec.To, spec.CT, spec.VT)
+			if err != nil {
+				return nil, fmt.Errorf("%s: meter %s: %w", filename, meter, err)
+			}
+			c.periods = append(c.periods, p)
+		}
+
+		err = sortPeriods(c.periods)
+		if err != nil {
+			return nil, fmt.Errorf("%s: meter %s: %w", filename, meter, err)
+		}
+	}
+
+	return r, nil
+}
+
+// Apply sets the settings of the registry to each App, every meter should be in the registry
+func (r Registry) Apply(apps []*App) error {
+	for _, a := range apps {
+		c, ok := r[a.Meter]
+		if !ok {
+			return fmt.Errorf("meter %s is not in the registry", a.Meter)
+		}
+
+		a.Contract = c.Contract
+		a.CompanyName = c.CompanyName
+		a.INN = c.INN
+		a.Coefficient = c.Coefficient
+		a.Coefficients = c.periods
+		a.Recipient = c.Recipient
+	}
+
+	return nil
+}
+
+// Batch converts each *.html export with the registry settings of its meters into its own directory
+// root/recipient/accountpoint code of the first meter/80020-MM-YYYY, workers files are converted at the same time,
+// the results are in the order of filenames
+func (r Registry) Batch(filenames []string, root string, workers int) []*BatchResult {
+	return r.batch(filenames, root, workers, newClaims())
+}
+
+// claims the month directories by the file converted into them, a directory is written by one file only
+type claims struct {
+	mu   sync.Mutex
+	dirs map[string]string
+}
+
+// newClaims return claims without directories
+func newClaims() *claims {
+	return &claims{dirs: make(map[string]string)}
+}
+
+// claim reserves the directory for the file, it fails if another file has reserved it
+func (c *claims) claim(dir, filename string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if other, ok := c.dirs[dir]; ok {
+		return fmt.Errorf("the same accountpoint and month as %s", other)
+	}
+	c.dirs[dir] = filename
+
+	return nil
+}
+
+// batch converts the files like Batch, the output directories are reserved in claimed
+func (r Registry) batch(filenames []string, root string, workers int, claimed *claims) []*BatchResult {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]*BatchResult, len(filenames))
+
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = r.convert(filenames[i], root, claimed)
+			}
+		}()
+	}
+
+	for i := range filenames {
+		indexes <- i
+	}
+	close(indexes)
+
+	wg.Wait()
+
+	return results
+}
+
+// convert converts a single file of the batch, the output directory is reserved for the file in claimed
+func (r Registry) convert(filename, root string, claimed *claims) *BatchResult {
+	result := &BatchResult{Filename: filename}
+
+	apps, err := NewAll(filename, "", Mapping{Coefficient: 1}, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	for _, a := range apps {
+		result.Meters = append(result.Meters, a.Meter)
+	}
+
+	err = r.Apply(apps)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	first := apps[0]
+
+	dir := filepath.Join(root, first.Contract+first.Meter)
+	if first.Recipient != nil && first.Recipient.Name != "" {
+		dir = filepath.Join(root, first.Recipient.Name, first.Contract+first.Meter)
+	}
+
+	err = claimed.claim(filepath.Join(dir, DirName(first.Month, first.Year)), filename)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Dir, err = RunAllIn(dir, apps)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	for _, a := range apps {
+		result.Total += a.Total
+	}
+
+	return result
+}
+
+// WriteBatchCSV writes the results separated by semicolons
+func WriteBatchCSV(w io.Writer, results []*BatchResult) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+
+	err := cw.Write([]string{"filename", "status", "meters", "total", "dir", "error"})
+	if err != nil {
+		return err
+	}
+
+	for _, b := range results {
+		status, message := "ok", ""
+		if b.Err != nil {
+			status, message = "failed", b.Err.Error()
+		}
+
+		err = cw.Write([]string{b.Filename, status, strings.Join(b.Meters, ","), decimal(b.Total, 2), b.Dir, message})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// Save writes the registry to the *.json file, the directory is created if it doesn't exist
+func (r Registry) Save(filename string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(filename), 0766)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRegistry(t *testing.T) {
+	got, err := LoadRegistry("testdata/registry.json")
+	if err != nil {
+		t.Fatalf("LoadRegistry() error = %v", err)
+	}
+
+	star := got["23456789"]
+	if star == nil || star.Contract != "98765432" || star.Coefficient != 4000 || star.Recipient.Name != "mosenergosbyt" {
+		t.Errorf("LoadRegistry() 23456789 = %+v", star)
+	}
+	moon := got["12345678"]
+	if moon == nil || moon.Coefficient != 1 || len(moon.periods) != 2 || moon.periods[1].Coefficient() != 60 {
+		t.Errorf("LoadRegistry() 12345678 = %+v", moon)
+	}
+
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"empty":    `{}`,
+		"contract": `{"23456789": {"company_name": "OOO STAR"}}`,
+		"overlap":  `{"23456789": {"contract": "1", "coefficients": [{"from": "01.02.2020 00:00", "ct": "1", "vt": "1"}, {"from": "02.02.2020 00:00", "ct": "2", "vt": "1"}]}}`,
+		"ratio":    `{"23456789": {"contract": "1", "coefficients": [{"from": "01.02.2020 00:00", "ct": "x", "vt": "1"}]}}`,
+		"syntax":   `{"23456789": `,
+	} {
+		filename := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadRegistry(filename); err == nil {
+			t.Errorf("LoadRegistry() of %s should fail", name)
+		}
+	}
+}
+
+func TestRegistry_Batch(t *testing.T) {
+	registry, err := LoadRegistry("testdata/registry.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	filenames := []string{
+		"testdata/two_meters.html",
+		"testdata/23456789_feb.html",
+		"testdata/empty.html",
+	}
+
+	got := registry.Batch(filenames, root, 2)
+	if len(got) != len(filenames) {
+		t.Fatalf("Batch() len = %d, want %d", len(got), len(filenames))
+	}
+
+	for i, b := range got {
+		if b.Filename != filenames[i] {
+			t.Errorf("Batch() result %d is %s, want %s", i, b.Filename, filenames[i])
+		}
+	}
+
+	// two_meters.html and 23456789_feb.html start with the same meter, only one of them is converted
+	var converted, claimed int
+	for _, b := range got[:2] {
+		if b.Err == nil {
+			converted++
+			if want := filepath.Join(root, "mosenergosbyt", "9876543223456789", "80020-02-2020"); b.Dir != want {
+				t.Errorf("Batch() dir = %s, want %s", b.Dir, want)
+			}
+		} else if strings.Contains(b.Err.Error(), "the same accountpoint") {
+			claimed++
+		}
+	}
+	if converted != 1 || claimed != 1 {
+		t.Errorf("Batch() converted %d and rejected %d files of the same accountpoint", converted, claimed)
+	}
+	if got[2].Err == nil {
+		t.Errorf("Batch() of a file without rows should fail")
+	}
+
+	var dir string
+	for _, b := range got[:2] {
+		if b.Err == nil {
+			dir = b.Dir
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "80020_001_98765432_01022020.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"<inn>7701234567</inn>", "<inn>7736520080</inn>", "<name>Moscow</name>"} {
+		if !bytes.Contains(data, []byte(s)) {
+			t.Errorf("Batch() file don't contain %s", s)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := WriteBatchCSV(&b, got); err != nil {
+		t.Fatalf("WriteBatchCSV() error = %v", err)
+	}
+	if lines := strings.Count(b.String(), "\n"); lines != len(filenames)+1 {
+		t.Errorf("WriteBatchCSV() = %s", b.String())
+	}
+}
+
+func TestRegistry_Apply(t *testing.T) {
+	registry, err := LoadRegistry("testdata/registry.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apps, err := NewAll("testdata/two_meters.html", "", Mapping{Coefficient: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := registry.Apply(apps); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if a := apps[1]; a.Contract != "11111111" || a.CompanyName != "OOO MOON" || len(a.Coefficients) != 2 {
+		t.Errorf("Apply() 12345678 = %s %s %v", a.Contract, a.CompanyName, a.Coefficients)
+	}
+
+	delete(registry, "12345678")
+	if err := registry.Apply(apps); err == nil {
+		t.Errorf("Apply() of a meter without settings should fail")
+	}
+}
+
+func TestRegistry_Save(t *testing.T) {
+	registry, err := LoadRegistry("testdata/registry.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "config", "meters.json")
+	if err := registry.Save(filename); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := LoadRegistry(filename)
+	if err != nil {
+		t.Fatalf("LoadRegistry() error = %v", err)
+	}
+	if len(got) != 2 || got["23456789"].INN != "7701234567" || len(got["12345678"].periods) != 2 {
+		t.Errorf("LoadRegistry() of the saved registry = %+v", got)
+	}
+}
@@ -0,0 +1,183 @@
+package app
+
+import (
+	"encoding/json"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path"
+	"text/template"
+	"time"
+)
+
+// summaryFiles the names of the summary files written next to the day files
+var summaryFiles = []string{"summary.txt", "summary.html", "summary.json"}
+
+// Report monthly summary of the converted meters
+type Report struct {
+	Month  int            `json:"month"`
+	Year   int            `json:"year"`
+	Meters []*MeterReport `json:"meters"`
+	Files  []string       `json:"files"` // the generated files
+}
+
+// MeterReport monthly summary of a single accountpoint
+type MeterReport struct {
+	Meter          string          `json:"meter"`
+	Contract       string          `json:"contract"`
+	Coefficients   []*PeriodReport `json:"coefficients"`
+	Sources        []*Source       `json:"sources"`
+	Total          float64         `json:"total"`
+	Values         int             `json:"values"`
+	Gaps           int             `json:"gaps"`      // hours of the month without a row
+	Estimated      int             `json:"estimated"` // rows with a note or an incomplete period
+	Capacity       float64         `json:"capacity,omitempty"`
+	CapacityError  string          `json:"capacity_error,omitempty"`
+	Zones          []*ZoneTotal    `json:"zones,omitempty"`
+	Reconciliation string          `json:"reconciliation,omitempty"`
+	Days           []*DayReport    `json:"days"`
+}
+
+// PeriodReport the coefficient applied to consecutive hours
+type PeriodReport struct {
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Coefficient float64 `json:"coefficient"`
+	Hours       int     `json:"hours"`
+}
+
+// DayReport totals of a single day
+type DayReport struct {
+	Date    string  `json:"date"`
+	Total   float64 `json:"total"`
+	MaxHour int     `json:"max_hour"` // the start of the hour with the highest consumption
+	Max     float64 `json:"max"`
+}
+
+// NewReport return the summary of the apps without writing any file
+func NewReport(apps []*App) (*Report, error) {
+	days, err := prepare(apps)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newReport(apps, days, nil)
+	r.Files = nil
+
+	return r, nil
+}
+
+// newReport return the summary of the daily values of the apps and the generated files
+func newReport(apps []*App, days [][][]float64, files []string) *Report {
+	r := &Report{Files: append(append([]string{}, files...), summaryFiles...)}
+	if len(apps) != 0 {
+		r.Month, r.Year = apps[0].Month, apps[0].Year
+	}
+
+	for i, a := range apps {
+		m := &MeterReport{
+			Meter:         a.Meter,
+			Contract:      a.Contract,
+			Sources:       a.Sources,
+			Total:         a.Total,
+			Values:        len(a.Rows),
+			Capacity:      a.Capacity,
+			Zones:         a.Zones,
+			CapacityError: a.CapacityErr,
+		}
+
+		for _, u := range a.CoefficientUsage() {
+			m.Coefficients = append(m.Coefficients, &PeriodReport{
+				From:        u.From.Format("02.01.2006 15:04"),
+				To:          u.To.Format("02.01.2006 15:04"),
+				Coefficient: u.Coefficient,
+				Hours:       u.Hours,
+			})
+		}
+
+		if a.Reconciliation != nil {
+			m.Reconciliation = a.Reconciliation.String()
+		}
+
+		present := make(map[time.Time]bool)
+		for _, row := range a.Rows {
+			start := row.Date.Add(-time.Hour)
+			if start.Year() != a.Year || int(start.Month()) != a.Month {
+				continue
+			}
+			present[start] = true
+			if row.Estimated() {
+				m.Estimated++
+			}
+		}
+		m.Gaps = a.DaysInMonth*24 - len(present)
+
+		for d, values := range days[i] {
+			day := &DayReport{Date: time.Date(a.Year, time.Month(a.Month), d+1, 0, 0, 0, 0, time.UTC).Format("02.01.2006")}
+			for h, v := range values {
+				day.Total += v
+				if v > day.Max {
+					day.Max, day.MaxHour = v, h
+				}
+			}
+			m.Days = append(m.Days, day)
+		}
+
+		r.Meters = append(r.Meters, m)
+	}
+
+	return r
+}
+
+// writeReport writes the summary as text, HTML and JSON files
+func writeReport(dirName string, r *Report) error {
+	writes := []func(w io.Writer) error{r.WriteText, r.WriteHTML, r.WriteJSON}
+
+	for i, write := range writes {
+		err := executeFile(path.Join(dirName, summaryFiles[i]), func(f *os.File) error { return write(f) })
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteText writes the summary as plain text
+func (r *Report) WriteText(w io.Writer) error {
+	t, err := template.ParseFS(templateFS, "template/summary_txt.tmpl")
+	if err != nil {
+		return err
+	}
+
+	return t.Execute(w, r)
+}
+
+// WriteHTML writes the summary as an HTML page
+func (r *Report) WriteHTML(w io.Writer) error {
+	t, err := htmltemplate.ParseFS(templateFS, "template/summary_html.tmpl")
+	if err != nil {
+		return err
+	}
+
+	return t.Execute(w, r)
+}
+
+// WriteJSON writes the summary as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+}
+
+// executeFile creates the file and writes it by write
+func executeFile(filename string, write func(f *os.File) error) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return write(f)
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewReport(t *testing.T) {
+	// the first two days of February without the fifth hour, the third hour is estimated
+	values := make([]float64, 48)
+	for i := range values {
+		values[i] = float64(i%24) + 1
+	}
+	rows := hourlyRows(values)
+	rows[2].Period = "30"
+	rows = append(rows[:4], rows[5:]...)
+
+	a := newApp(&Profile{Meter: "23456789", Rows: rows, Sources: []*Source{{Filename: "feb.html", SHA256: "abc"}}}, "98765432", "OOO STAR", "", 2)
+
+	got := newReport([]*App{a}, [][][]float64{a.daily()}, []string{"80020_001_98765432_01022020.xml"})
+
+	if got.Month != 2 || got.Year != 2020 || len(got.Meters) != 1 {
+		t.Fatalf("newReport() = %+v", got)
+	}
+	if want := []string{"80020_001_98765432_01022020.xml", "summary.txt", "summary.html", "summary.json"}; strings.Join(got.Files, ",") != strings.Join(want, ",") {
+		t.Errorf("newReport() Files = %v, want %v", got.Files, want)
+	}
+
+	m := got.Meters[0]
+	if m.Gaps != 29*24-47 || m.Estimated != 1 || m.Values != 47 {
+		t.Errorf("newReport() gaps %d, estimated %d, values %d", m.Gaps, m.Estimated, m.Values)
+	}
+	if len(m.Coefficients) != 1 || m.Coefficients[0].Coefficient != 2 || m.Coefficients[0].Hours != 47 {
+		t.Errorf("newReport() Coefficients = %+v", m.Coefficients)
+	}
+	if len(m.Sources) != 1 || m.Sources[0].SHA256 != "abc" {
+		t.Errorf("newReport() Sources = %+v", m.Sources)
+	}
+
+	if len(m.Days) != 29 {
+		t.Fatalf("newReport() days = %d, want 29", len(m.Days))
+	}
+	first := m.Days[0]
+	if first.Date != "01.02.2020" || first.Total != (300-5)*2 || first.MaxHour != 23 || first.Max != 48 {
+		t.Errorf("newReport() first day = %+v", first)
+	}
+	if last := m.Days[28]; last.Total != 0 || last.Max != 0 {
+		t.Errorf("newReport() last day = %+v", last)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	a := newApp(&Profile{Meter: "23456789", Rows: hourlyRows([]float64{1, 2, 3})}, "98765432", "OOO STAR", "", 1)
+	r := newReport([]*App{a}, [][][]float64{a.daily()}, nil)
+
+	dirName := t.TempDir()
+	if err := writeReport(dirName, r); err != nil {
+		t.Fatalf("writeReport() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path.Join(dirName, "summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &Report{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("summary.json: %v", err)
+	}
+	if len(got.Meters) != 1 || got.Meters[0].Total != 6 || got.Meters[0].Days[0].MaxHour != 2 {
+		t.Errorf("summary.json = %s", data)
+	}
+
+	for _, name := range []string{"summary.txt", "summary.html"} {
+		data, err := os.ReadFile(path.Join(dirName, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "23456789") || !strings.Contains(string(data), "01.02.2020") {
+			t.Errorf("%s don't contain the meter and its days", name)
+		}
+	}
+}
+
+func TestReport_WriteText(t *testing.T) {
+	a := newApp(&Profile{Meter: "23456789", Rows: hourlyRows([]float64{1, 2, 3})}, "98765432", "OOO STAR", "", 2)
+
+	r, err := NewReport([]*App{a})
+	if err != nil {
+		t.Fatalf("NewReport() error = %v", err)
+	}
+	if len(r.Files) != 0 || a.Total != 12 {
+		t.Errorf("NewReport() files %v, total %v", r.Files, a.Total)
+	}
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	if !strings.Contains(b.String(), "total:	12.00 kWh") || strings.Contains(b.String(), "files:") {
+		t.Errorf("WriteText() = %s", b.String())
+	}
+
+	march := newApp(&Profile{Meter: "12345678", Rows: []*Row{{PPlus: 1, Date: time.Date(2020, 3, 1, 1, 0, 0, 0, time.UTC)}}}, "98765432", "OOO STAR", "", 1)
+	if _, err := NewReport([]*App{a, march}); err == nil {
+		t.Errorf("NewReport() of different months should fail")
+	}
+}
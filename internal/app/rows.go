@@ -19,14 +19,32 @@ type Row struct {
 	Date   time.Time
 }
 
-// parse analyzes row by row and finds values
-func parse(data []byte) ([]*Row, string, error) {
+// Profile all rows of a single meter table
+type Profile struct {
+	Meter   string
+	Rows    []*Row
+	Sources []*Source // the files the rows are read from
+}
+
+// Source the file of the meter export
+type Source struct {
+	Filename string `json:"filename"`
+	SHA256   string `json:"sha256"`
+}
+
+// Estimated return true if the row has a note or doesn't cover both half-hours
+func (r *Row) Estimated() bool {
+	return (r.Note != "" && r.Note != "-") || (r.Period != "" && r.Period != "30+30")
+}
+
+// parse analyzes row by row and finds values of each meter table
+func parse(data []byte) ([]*Profile, error) {
 	if len(data) == 0 {
-		return nil, "", errors.New("bad data: shouldn't be empty")
+		return nil, errors.New("bad data: shouldn't be empty")
 	}
 
-	var rows []*Row
-	var meter string
+	var profiles []*Profile
+	var profile *Profile
 
 	// ten values per row
 	var tenRows []string
@@ -37,17 +55,25 @@ func parse(data []byte) ([]*Row, string, error) {
 			if len(tenRows) == 10 {
 				r, err := toRows(tenRows)
 				if err != nil {
-					return nil, "", err
+					return nil, err
+				}
+				if profile == nil {
+					profile = &Profile{}
+					profiles = append(profiles, profile)
 				}
-				rows = append(rows, r)
+				profile.Rows = append(profile.Rows, r)
+				tenRows = nil
+			}
+		} else if bytes.Contains(row, []byte("H2")) {
+			if meter := serial(row); meter != "" { // each meter table starts with its own H2
+				profile = &Profile{Meter: meter}
+				profiles = append(profiles, profile)
 				tenRows = nil
 			}
-		} else if bytes.Contains(row, []byte("H2")) && meter == "" {
-			meter = serial(row)
 		}
 	}
 
-	return rows, meter, nil
+	return profiles, nil
 }
 
 // toRows cast type
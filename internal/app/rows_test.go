@@ -195,15 +195,13 @@ func Test_parse(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		want    []*Row
-		want1   string
+		want    []*Profile
 		wantErr bool
 	}{
 		{
 			name:    "data empty",
 			args:    args{},
 			want:    nil,
-			want1:   "",
 			wantErr: true,
 		},
 		{
@@ -253,19 +251,23 @@ func Test_parse(t *testing.T) {
 					  <TD class=style21>1580518800000</TD></TR>
 					  <TR>`),
 			},
-			want: []*Row{
+			want: []*Profile{
 				{
-					ID:     2,
-					PPlus:  0.0705,
-					PMinus: 0,
-					QPlus:  0.0063,
-					QMinus: 0.0019,
-					Period: "30+30",
-					Note:   "-",
-					Date:   time.Date(2020, 2, 1, 1, 0, 0, 0, time.UTC),
+					Meter: "23456789",
+					Rows: []*Row{
+						{
+							ID:     2,
+							PPlus:  0.0705,
+							PMinus: 0,
+							QPlus:  0.0063,
+							QMinus: 0.0019,
+							Period: "30+30",
+							Note:   "-",
+							Date:   time.Date(2020, 2, 1, 1, 0, 0, 0, time.UTC),
+						},
+					},
 				},
 			},
-			want1:   "23456789",
 			wantErr: false,
 		},
 		{
@@ -284,13 +286,72 @@ func Test_parse(t *testing.T) {
 					  <TD class=style21>fail</TD></TR>`),
 			},
 			want:    nil,
-			want1:   "",
 			wantErr: true,
 		},
+		{
+			name: "two meters",
+			args: args{
+				data: []byte(`<H2>М234 (Сетевой адрес - 17, Серийный номер - 23456789)</H2>
+					  <H2></H2>
+					  <TD class=style21>2</TD>
+					  <TD class=style21>0.0705</TD>
+					  <TD class=style21>0.0000</TD>
+					  <TD class=style21>0.0063</TD>
+					  <TD class=style21>0.0019</TD>
+					  <TD class=style21>01:00</TD>
+					  <TD class=style21>01.02.20</TD>
+					  <TD class=style21>30+30</TD>
+					  <TD class=style21>-</TD>
+					  <TD class=style21>1580518800000</TD></TR>
+					  <H2>М234 (Сетевой адрес - 18, Серийный номер - 12345678)</H2>
+					  <H2></H2>
+					  <TD class=style21>3</TD>
+					  <TD class=style21>0.1410</TD>
+					  <TD class=style21>0.0000</TD>
+					  <TD class=style21>0.0063</TD>
+					  <TD class=style21>0.0019</TD>
+					  <TD class=style21>01:00</TD>
+					  <TD class=style21>01.02.20</TD>
+					  <TD class=style21>30+30</TD>
+					  <TD class=style21>-</TD>
+					  <TD class=style21>1580518800000</TD></TR>`),
+			},
+			want: []*Profile{
+				{
+					Meter: "23456789",
+					Rows: []*Row{
+						{
+							ID:     2,
+							PPlus:  0.0705,
+							QPlus:  0.0063,
+							QMinus: 0.0019,
+							Period: "30+30",
+							Note:   "-",
+							Date:   time.Date(2020, 2, 1, 1, 0, 0, 0, time.UTC),
+						},
+					},
+				},
+				{
+					Meter: "12345678",
+					Rows: []*Row{
+						{
+							ID:     3,
+							PPlus:  0.1410,
+							QPlus:  0.0063,
+							QMinus: 0.0019,
+							Period: "30+30",
+							Note:   "-",
+							Date:   time.Date(2020, 2, 1, 1, 0, 0, 0, time.UTC),
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, got1, err := parse(tt.args.data)
+			got, err := parse(tt.args.data)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parse() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -298,9 +359,6 @@ func Test_parse(t *testing.T) {
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("parse() got = %v, want %v", got, tt.want)
 			}
-			if got1 != tt.want1 {
-				t.Errorf("parse() got1 = %v, want %v", got1, tt.want1)
-			}
 		})
 	}
 }
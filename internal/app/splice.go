@@ -0,0 +1,183 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Segment a meter of the accountpoint valid for the hours starting from From up to To,
+// zero From and To are open ends
+type Segment struct {
+	Filename    string
+	Meter       string
+	Coefficient float64
+	From        time.Time
+	To          time.Time
+}
+
+// LoadSegments reads the meter segments from the *.csv file with rows "filename;meter;coefficient;from;to",
+// dates are "02.01.2006 15:04" in the meter time, filenames are relative to the *.csv file
+func LoadSegments(filename string) ([]*Segment, error) {
+	return loadSegments(filename, func(name string) (string, error) {
+		if filepath.IsAbs(name) {
+			return name, nil
+		}
+		return filepath.Join(filepath.Dir(filename), name), nil
+	})
+}
+
+// LoadUploadedSegments reads the meter segments as LoadSegments does, the filenames should be
+// one of the names of the uploaded exports in the directory of the *.csv file
+func LoadUploadedSegments(filename string, names []string) ([]*Segment, error) {
+	return loadSegments(filename, func(name string) (string, error) {
+		if name != filepath.Base(name) || strings.ContainsAny(name, `/\:`) || name == "." || name == ".." {
+			return "", fmt.Errorf("%s: a name of an uploaded export required", name)
+		}
+		for _, n := range names {
+			if n == name {
+				return filepath.Join(filepath.Dir(filename), name), nil
+			}
+		}
+		return "", fmt.Errorf("%s: the export is not uploaded", name)
+	})
+}
+
+// loadSegments reads the meter segments, resolve return the path of a segment filename
+func loadSegments(filename string, resolve func(name string) (string, error)) ([]*Segment, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []*Segment
+
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		row := strings.TrimSpace(string(line))
+		if row == "" || strings.HasPrefix(row, "filename") { // skips the header
+			continue
+		}
+
+		fields := strings.Split(row, ";")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("%s:%d: filename;meter;coefficient;from;to required", filename, i+1)
+		}
+
+		s := &Segment{Meter: strings.TrimSpace(fields[1]), Coefficient: 1}
+
+		s.Filename, err = resolve(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, i+1, err)
+		}
+
+		if v := strings.TrimSpace(fields[2]); v != "" {
+			s.Coefficient, err = strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", filename, i+1, err)
+			}
+		}
+
+		if v := strings.TrimSpace(fields[3]); v != "" {
+			s.From, err = time.Parse("02.01.2006 15:04", v)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", filename, i+1, err)
+			}
+		}
+
+		if v := strings.TrimSpace(fields[4]); v != "" {
+			s.To, err = time.Parse("02.01.2006 15:04", v)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", filename, i+1, err)
+			}
+		}
+
+		segments = append(segments, s)
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("%s: no segments found", filename)
+	}
+
+	return segments, nil
+}
+
+// NewSpliced return App of the accountpoint whose hourly series is spliced from the meter segments,
+// the segments should neither overlap nor leave hours between them,
+// each segment keeps its coefficient in the coefficient history
+func NewSpliced(segments []*Segment, contract, companyName, code string) (*App, error) {
+	if len(segments) == 0 {
+		return nil, errors.New("there should be at least one segment")
+	}
+
+	sorted := make([]*Segment, len(segments))
+	copy(sorted, segments)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].From.Before(sorted[j].From) })
+
+	for i := 1; i < len(sorted); i++ {
+		prev, next := sorted[i-1], sorted[i]
+		if prev.To.IsZero() || prev.To.After(next.From) {
+			return nil, fmt.Errorf("segments of meters %s and %s overlap", prev.Meter, next.Meter)
+		}
+	}
+
+	spliced := &Profile{Meter: code}
+	var coefficients []*CoefficientPeriod
+	var changeovers []int // the index of the first row of each next segment
+
+	for i, s := range sorted {
+		profiles, err := readProfiles(s.Filename)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Filename, err)
+		}
+
+		p, err := selectProfile(profiles, s.Meter)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Filename, err)
+		}
+		if s.Meter != "" && p.Meter != "" && p.Meter != s.Meter {
+			return nil, fmt.Errorf("%s: meter %s not found", s.Filename, s.Meter)
+		}
+
+		spliced.Sources = append(spliced.Sources, p.Sources...)
+
+		period := &CoefficientPeriod{From: s.From, To: s.To, CT: s.Coefficient, VT: 1}
+
+		if i > 0 {
+			changeovers = append(changeovers, len(spliced.Rows))
+		}
+
+		var n int
+		for _, r := range p.Rows {
+			if period.contains(r.Date.Add(-time.Hour)) {
+				spliced.Rows = append(spliced.Rows, r)
+				n++
+			}
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("%s: meter %s has no rows within its segment", s.Filename, s.Meter)
+		}
+
+		coefficients = append(coefficients, period)
+	}
+
+	// the hours missed at the changeover would silently drop out of the month,
+	// the gaps within a segment are counted by the report
+	for _, i := range changeovers {
+		prev, next := spliced.Rows[i-1].Date, spliced.Rows[i].Date
+		if next.Sub(prev) != time.Hour {
+			return nil, fmt.Errorf("gap at the changeover from %s to %s",
+				prev.Format("02.01.2006 15:04"), next.Add(-time.Hour).Format("02.01.2006 15:04"))
+		}
+	}
+
+	a := newApp(spliced, contract, companyName, code, 1)
+	a.Coefficients = coefficients
+
+	return a, nil
+}
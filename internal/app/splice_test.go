@@ -0,0 +1,170 @@
+package app
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadSegments(t *testing.T) {
+	got, err := LoadSegments("testdata/segments_feb.csv")
+	if err != nil {
+		t.Fatalf("LoadSegments() error = %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("LoadSegments() len = %d, want 2", len(got))
+	}
+	if got[0].Filename != filepath.Join("testdata", "23456789_feb.html") || !got[0].From.IsZero() || got[1].Coefficient != 2 {
+		t.Errorf("LoadSegments() = %+v %+v", got[0], got[1])
+	}
+
+	if _, err := LoadSegments("testdata/peak_hours_feb.csv"); err == nil {
+		t.Errorf("LoadSegments() of a bad file should fail")
+	}
+}
+
+func TestLoadUploadedSegments(t *testing.T) {
+	names := []string{"23456789_feb.html"}
+
+	got, err := LoadUploadedSegments("testdata/segments_feb.csv", names)
+	if err != nil {
+		t.Fatalf("LoadUploadedSegments() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Filename != filepath.Join("testdata", "23456789_feb.html") {
+		t.Errorf("LoadUploadedSegments() = %+v", got)
+	}
+
+	tests := []struct {
+		name string
+		row  string
+	}{
+		{"absolute", "/etc/passwd;23456789;1;;"},
+		{"parent", "../x.html;23456789;1;;"},
+		{"subdir", "testdata/23456789_feb.html;23456789;1;;"},
+		{"not uploaded", "other.html;23456789;1;;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "segments.csv")
+			if err := os.WriteFile(filename, []byte(tt.row+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := LoadUploadedSegments(filename, append(names, "passwd", "x.html")); err == nil {
+				t.Errorf("LoadUploadedSegments() of %q should fail", tt.row)
+			}
+		})
+	}
+}
+
+func TestNewSpliced(t *testing.T) {
+	change := time.Date(2020, 2, 15, 10, 0, 0, 0, time.UTC)
+	file := "testdata/23456789_feb.html"
+
+	tests := []struct {
+		name     string
+		segments []*Segment
+		wantErr  string
+	}{
+		{
+			name: "changeover",
+			segments: []*Segment{
+				{Filename: file, Meter: "23456789", Coefficient: 2, From: change},
+				{Filename: file, Meter: "23456789", Coefficient: 1, To: change},
+			},
+		},
+		{
+			name: "overlap",
+			segments: []*Segment{
+				{Filename: file, Meter: "23456789", Coefficient: 1, To: change.Add(2 * time.Hour)},
+				{Filename: file, Meter: "23456789", Coefficient: 2, From: change},
+			},
+			wantErr: "overlap",
+		},
+		{
+			name: "gap",
+			segments: []*Segment{
+				{Filename: file, Meter: "23456789", Coefficient: 1, To: change},
+				{Filename: file, Meter: "23456789", Coefficient: 2, From: change.Add(2 * time.Hour)},
+			},
+			wantErr: "gap at the changeover from 15.02.2020 10:00 to 15.02.2020 12:00",
+		},
+		{
+			name:     "unknown meter",
+			segments: []*Segment{{Filename: "testdata/two_meters.html", Meter: "99999999", Coefficient: 1}},
+			wantErr:  "meter 99999999 not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewSpliced(tt.segments, "98765432", "OOO STAR", "00000001")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewSpliced() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewSpliced() error = %v", err)
+			}
+
+			if got.Meter != "00000001" || len(got.Rows) != 29*24 || len(got.Coefficients) != 2 {
+				t.Errorf("NewSpliced() = %s, %d rows, %d coefficients", got.Meter, len(got.Rows), len(got.Coefficients))
+			}
+
+			original, err := New(file, "", "", "", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			original.daily()
+
+			var before float64
+			for _, r := range original.Rows {
+				if r.Date.Add(-time.Hour).Before(change) {
+					before += r.PPlus
+				}
+			}
+
+			got.daily()
+			if want := original.Total*2 - before; math.Abs(got.Total-want) > 1e-9 {
+				t.Errorf("NewSpliced() Total = %v, want %v", got.Total, want)
+			}
+		})
+	}
+}
+
+func TestNewSpliced_gapInSegment(t *testing.T) {
+	data, err := os.ReadFile("testdata/23456789_feb.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the row of 03.02.2020 01:00 - 02:00 is removed from the first segment
+	text := string(data)
+	i := strings.Index(text, "<TD class=style21>02:00</TD>\r\n<TD class=style21>03.02.20</TD>")
+	if i == -1 {
+		t.Fatal("the row of 03.02.2020 02:00 not found")
+	}
+	start := strings.LastIndex(text[:i], "<TR>")
+	end := i + strings.Index(text[i:], "<TR>")
+	file := filepath.Join(t.TempDir(), "gap.html")
+	if err := os.WriteFile(file, []byte(text[:start]+text[end:]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	change := time.Date(2020, 2, 15, 10, 0, 0, 0, time.UTC)
+	got, err := NewSpliced([]*Segment{
+		{Filename: file, Meter: "23456789", Coefficient: 1, To: change},
+		{Filename: "testdata/23456789_feb.html", Meter: "23456789", Coefficient: 2, From: change},
+	}, "98765432", "OOO STAR", "00000001")
+	if err != nil {
+		t.Fatalf("NewSpliced() error = %v", err)
+	}
+	if len(got.Rows) != 29*24-1 {
+		t.Errorf("NewSpliced() rows = %d, want %d", len(got.Rows), 29*24-1)
+	}
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Watcher converts the *.html exports appearing in the inbox directory with the registry settings
+type Watcher struct {
+	Registry Registry
+	Inbox    string        // the directory polled for new exports
+	Outbox   string        // the converted files go to recipient/accountpoint/80020-MM-YYYY under it
+	Archive  string        // the converted exports are moved here
+	Errors   string        // the failed exports are moved here with a *.log file of the error
+	Settle   time.Duration // a file is converted when it hasn't changed for this time and between two polls
+	Workers  int
+
+	seen    map[string]fileState
+	claimed *claims // the outbox directories of all the polls, a later export of the same month is refused
+}
+
+// fileState the size and the modification time of a file seen by the previous poll
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// NewWatcher return Watcher of the directories, they are created if they don't exist
+func NewWatcher(registry Registry, inbox, outbox, archive, failed string) (*Watcher, error) {
+	for _, dir := range []string{inbox, outbox, archive, failed} {
+		if dir == "" {
+			return nil, errors.New("inbox, outbox, archive and errors directories required")
+		}
+		err := os.MkdirAll(dir, 0766)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Watcher{
+		Registry: registry,
+		Inbox:    inbox,
+		Outbox:   outbox,
+		Archive:  archive,
+		Errors:   failed,
+		Settle:   5 * time.Second,
+		Workers:  1,
+		seen:     make(map[string]fileState),
+		claimed:  newClaims(),
+	}, nil
+}
+
+// Poll converts the exports of the inbox which are no longer written and moves them
+// to the archive or to the errors directory, the results are in the order of the file names,
+// an export of the accountpoint and month converted by an earlier poll goes to the errors directory
+func (w *Watcher) Poll(now time.Time) ([]*BatchResult, error) {
+	ready, err := w.ready(now)
+	if err != nil {
+		return nil, err
+	}
+	if len(ready) == 0 {
+		return nil, nil
+	}
+
+	results := w.Registry.batch(ready, w.Outbox, w.Workers, w.claimed)
+
+	for _, b := range results {
+		delete(w.seen, b.Filename)
+
+		if b.Err != nil {
+			err = w.fail(b, now)
+		} else {
+			err = moveFile(b.Filename, filepath.Join(w.Archive, now.Format("20060102-150405_")+filepath.Base(b.Filename)))
+		}
+		if err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+// ready return the exports of the inbox whose size and modification time are the same as at the previous poll
+// and which were last modified at least Settle ago
+func (w *Watcher) ready(now time.Time) ([]string, error) {
+	entries, err := os.ReadDir(w.Inbox)
+	if err != nil {
+		return nil, err
+	}
+
+	var ready []string
+	present := make(map[string]bool)
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".html") {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			continue // the file is gone since the directory was read
+		}
+
+		filename := filepath.Join(w.Inbox, e.Name())
+		present[filename] = true
+
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		if prev, ok := w.seen[filename]; ok && prev == state && now.Sub(state.modTime) >= w.Settle {
+			ready = append(ready, filename)
+			continue
+		}
+		w.seen[filename] = state
+	}
+
+	for filename := range w.seen {
+		if !present[filename] {
+			delete(w.seen, filename)
+		}
+	}
+
+	sort.Strings(ready)
+
+	return ready, nil
+}
+
+// fail moves the export to the errors directory and writes the error next to it
+func (w *Watcher) fail(b *BatchResult, now time.Time) error {
+	name := now.Format("20060102-150405_") + filepath.Base(b.Filename)
+
+	err := moveFile(b.Filename, filepath.Join(w.Errors, name))
+	if err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("%s\n%s\n%s\n", now.Format("02.01.2006 15:04:05"), b.Filename, b.Err)
+
+	return os.WriteFile(filepath.Join(w.Errors, strings.TrimSuffix(name, filepath.Ext(name))+".log"), []byte(text), 0644)
+}
+
+// moveFile renames the file, it is copied and removed if the directories are on different disks
+func moveFile(from, to string) error {
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.Create(to)
+	if err != nil {
+		src.Close()
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	src.Close()
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(to)
+		return err
+	}
+
+	return os.Remove(from)
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcher_Poll(t *testing.T) {
+	registry, err := LoadRegistry("testdata/registry.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	dir := func(name string) string { return filepath.Join(root, name) }
+
+	w, err := NewWatcher(registry, dir("inbox"), dir("outbox"), dir("archive"), dir("errors"))
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	w.Settle = time.Minute
+
+	copyFile := func(from, name string) {
+		data, err := os.ReadFile(from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(w.Inbox, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	copyFile("testdata/23456789_feb.html", "feb.html")
+	copyFile("testdata/empty.html", "empty.html")
+	copyFile("testdata/zones.json", "zones.json")
+
+	now := time.Now().Add(2 * time.Minute)
+
+	// the first poll only notices the files
+	got, err := w.Poll(now)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("Poll() = %v, %v, want nothing", got, err)
+	}
+
+	// a file modified within Settle is still being written
+	got, err = w.Poll(time.Now())
+	if err != nil || len(got) != 0 {
+		t.Fatalf("Poll() = %v, %v, want nothing", got, err)
+	}
+
+	got, err = w.Poll(now)
+	if err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Err == nil || got[1].Err != nil {
+		t.Fatalf("Poll() = %v", got)
+	}
+
+	archived, _ := filepath.Glob(filepath.Join(w.Archive, "*_feb.html"))
+	if len(archived) != 1 {
+		t.Errorf("Poll() archive = %v", archived)
+	}
+	if _, err := os.Stat(filepath.Join(got[1].Dir, "summary.txt")); err != nil {
+		t.Errorf("Poll() outbox: %v", err)
+	}
+
+	logs, _ := filepath.Glob(filepath.Join(w.Errors, "*_empty.log"))
+	if len(logs) != 1 {
+		t.Fatalf("Poll() errors = %v", logs)
+	}
+	data, err := os.ReadFile(logs[0])
+	if err != nil || !strings.Contains(string(data), "empty.html") {
+		t.Errorf("Poll() log = %s, %v", data, err)
+	}
+
+	left, _ := os.ReadDir(w.Inbox)
+	if len(left) != 1 || left[0].Name() != "zones.json" {
+		t.Errorf("Poll() left %v in the inbox", left)
+	}
+
+	// a later export of the same accountpoint and month doesn't overwrite the outbox
+	summary := filepath.Join(got[1].Dir, "summary.txt")
+	written := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(summary, written, written); err != nil {
+		t.Fatal(err)
+	}
+	copyFile("testdata/23456789_feb.html", "feb_again.html")
+
+	if got, err := w.Poll(now); err != nil || len(got) != 0 {
+		t.Fatalf("Poll() = %v, %v, want nothing", got, err)
+	}
+	got, err = w.Poll(now)
+	if err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Err == nil || !strings.Contains(got[0].Err.Error(), "the same accountpoint and month as") {
+		t.Fatalf("Poll() = %v, want the export refused", got)
+	}
+
+	logs, _ = filepath.Glob(filepath.Join(w.Errors, "*_feb_again.log"))
+	if len(logs) != 1 {
+		t.Errorf("Poll() errors = %v", logs)
+	}
+	if info, err := os.Stat(summary); err != nil || !info.ModTime().Equal(written) {
+		t.Errorf("Poll() overwrote the outbox: %v", err)
+	}
+}
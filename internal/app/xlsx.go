@@ -0,0 +1,150 @@
+package app
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xlsxFiles the fixed parts of a workbook with a single sheet
+var xlsxFiles = []struct {
+	Name string
+	Data string
+}{
+	{
+		Name: "[Content_Types].xml",
+		Data: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`,
+	},
+	{
+		Name: "_rels/.rels",
+		Data: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`,
+	},
+	{
+		Name: "xl/_rels/workbook.xml.rels",
+		Data: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`,
+	},
+}
+
+// writeXLSX writes the table as a workbook with a single sheet,
+// float64 values are numbers and the others are strings
+func writeXLSX(w io.Writer, sheet string, table [][]interface{}) error {
+	zw := zip.NewWriter(w)
+
+	for _, file := range xlsxFiles {
+		f, err := zw.Create(file.Name)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(f, file.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.Create("xl/workbook.xml")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(f, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`, escapeXML(sheetName(sheet)))
+	if err != nil {
+		return err
+	}
+
+	f, err = zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+
+	err = writeSheet(f, table)
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// writeSheet writes the worksheet part of the table
+func writeSheet(w io.Writer, table [][]interface{}) error {
+	var b strings.Builder
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	for i, row := range table {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		for j, v := range row {
+			ref := columnName(j) + strconv.Itoa(i+1)
+			switch v := v.(type) {
+			case float64:
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+			case nil:
+			default:
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, escapeXML(fmt.Sprint(v)))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+
+	b.WriteString(`</sheetData></worksheet>`)
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// sheetName return the name without the characters forbidden in sheet names, at most 31 characters long
+func sheetName(s string) string {
+	name := []rune(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, s))
+
+	if len(name) > 31 {
+		name = name[:31]
+	}
+	if len(name) == 0 {
+		return "profile"
+	}
+
+	return string(name)
+}
+
+// columnName return the letters of the zero-based column: A, B, ..., Z, AA
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+
+	return name
+}
+
+// escapeXML return s with the XML special characters escaped
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestColumnName(t *testing.T) {
+	tests := []struct {
+		i    int
+		want string
+	}{
+		{i: 0, want: "A"},
+		{i: 25, want: "Z"},
+		{i: 26, want: "AA"},
+		{i: 27, want: "AB"},
+		{i: 701, want: "ZZ"},
+		{i: 702, want: "AAA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := columnName(tt.i); got != tt.want {
+				t.Errorf("columnName() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "23456789", want: "23456789"},
+		{s: "a/b:c", want: "a_b_c"},
+		{s: "", want: "profile"},
+		{s: strings.Repeat("x", 40), want: strings.Repeat("x", 31)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := sheetName(tt.s); got != tt.want {
+				t.Errorf("sheetName() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	buff := new(bytes.Buffer)
+	table := [][]interface{}{
+		{"date", "p_plus"},
+		{"01.02.2020", 1.25},
+		{"<&>", nil},
+	}
+
+	if err := writeXLSX(buff, "23456789", table); err != nil {
+		t.Fatalf("writeXLSX() error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		t.Fatalf("writeXLSX() is not a zip: %v", err)
+	}
+
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = string(data)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("writeXLSX() has no part %s", name)
+		}
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="B2"><v>1.25</v></c>`,
+		`<c r="A1" t="inlineStr"><is><t>date</t></is></c>`,
+		`<t>&lt;&amp;&gt;</t>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("writeXLSX() sheet don't contain %s", want)
+		}
+	}
+	if strings.Contains(sheet, `r="B3"`) {
+		t.Errorf("writeXLSX() should skip empty cells")
+	}
+}
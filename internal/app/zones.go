@@ -0,0 +1,173 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Zone a tariff zone, each range is [from, to) hours of the day
+type Zone struct {
+	Name   string   `json:"name"`
+	Ranges [][2]int `json:"ranges"`
+}
+
+// Schedule tariff zones by month, zero month is used for months without their own zones
+type Schedule struct {
+	Name   string          `json:"name"`
+	Shift  int             `json:"shift"` // hours added to the meter time to get the time of the schedule
+	Months map[int][]*Zone `json:"months"`
+}
+
+// ZoneTotal consumption of a single zone
+type ZoneTotal struct {
+	Name  string  `json:"name"`
+	Total float64 `json:"total"`
+}
+
+// DayNightSchedule return the two zones schedule
+func DayNightSchedule() *Schedule {
+	return &Schedule{
+		Name: "day-night",
+		Months: map[int][]*Zone{
+			0: {
+				{Name: "day", Ranges: [][2]int{{7, 23}}},
+				{Name: "night", Ranges: [][2]int{{23, 24}, {0, 7}}},
+			},
+		},
+	}
+}
+
+// ThreeZoneSchedule return the peak, semi-peak and night schedule
+func ThreeZoneSchedule() *Schedule {
+	return &Schedule{
+		Name: "three-zone",
+		Months: map[int][]*Zone{
+			0: {
+				{Name: "peak", Ranges: [][2]int{{7, 10}, {17, 21}}},
+				{Name: "semi-peak", Ranges: [][2]int{{10, 17}, {21, 23}}},
+				{Name: "night", Ranges: [][2]int{{23, 24}, {0, 7}}},
+			},
+		},
+	}
+}
+
+// LoadSchedule return a built-in schedule by name or reads it from the *.json file
+func LoadSchedule(name string) (*Schedule, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "day-night":
+		return DayNightSchedule(), nil
+	case "three-zone":
+		return ThreeZoneSchedule(), nil
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schedule{}
+
+	err = json.Unmarshal(data, s)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Validate checks that the zones of each month cover every hour of the day exactly once
+// and that every month has zones, its own or the ones of month 0
+func (s *Schedule) Validate() error {
+	if len(s.Months) == 0 {
+		return fmt.Errorf("schedule %s: there should be zones", s.Name)
+	}
+
+	if _, ok := s.Months[0]; !ok {
+		for month := 1; month <= 12; month++ {
+			if _, ok := s.Months[month]; !ok {
+				return fmt.Errorf("schedule %s: month %d has no zones and there is no month 0", s.Name, month)
+			}
+		}
+	}
+
+	for month, zones := range s.Months {
+		if month < 0 || month > 12 {
+			return fmt.Errorf("schedule %s: bad month %d", s.Name, month)
+		}
+
+		var hours [24]int
+		for _, z := range zones {
+			for _, r := range z.Ranges {
+				if r[0] < 0 || r[1] > 24 || r[0] >= r[1] {
+					return fmt.Errorf("schedule %s: zone %s has bad range %d-%d", s.Name, z.Name, r[0], r[1])
+				}
+				for h := r[0]; h < r[1]; h++ {
+					hours[h]++
+				}
+			}
+		}
+
+		for h, n := range hours {
+			if n != 1 {
+				return fmt.Errorf("schedule %s: month %d hour %d belongs to %d zones", s.Name, month, h, n)
+			}
+		}
+	}
+
+	return nil
+}
+
+// zones return the zones of the month
+func (s *Schedule) zones(month time.Month) []*Zone {
+	if zones, ok := s.Months[int(month)]; ok {
+		return zones
+	}
+
+	return s.Months[0]
+}
+
+// zone return the zone name of the hour starting at t in the meter time
+func (s *Schedule) zone(t time.Time) string {
+	t = t.Add(time.Duration(s.Shift) * time.Hour)
+
+	for _, z := range s.zones(t.Month()) {
+		for _, r := range z.Ranges {
+			if t.Hour() >= r[0] && t.Hour() < r[1] {
+				return z.Name
+			}
+		}
+	}
+
+	return ""
+}
+
+// newZoneTotals return empty totals of the zones of the month
+func (s *Schedule) newZoneTotals(month time.Month) []*ZoneTotal {
+	var totals []*ZoneTotal
+	for _, z := range s.zones(month) {
+		totals = append(totals, &ZoneTotal{Name: z.Name})
+	}
+
+	return totals
+}
+
+// addZone adds v to the total of the zone
+func addZone(totals []*ZoneTotal, name string, v float64) []*ZoneTotal {
+	for _, z := range totals {
+		if z.Name == name {
+			z.Total += v
+			return totals
+		}
+	}
+
+	return append(totals, &ZoneTotal{Name: name, Total: v})
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		wantErr  bool
+	}{
+		{
+			name:     "day-night",
+			schedule: DayNightSchedule(),
+		},
+		{
+			name:     "three-zone",
+			schedule: ThreeZoneSchedule(),
+		},
+		{
+			name:     "no zones",
+			schedule: &Schedule{},
+			wantErr:  true,
+		},
+		{
+			name: "hour without zone",
+			schedule: &Schedule{Months: map[int][]*Zone{
+				0: {{Name: "day", Ranges: [][2]int{{7, 23}}}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "hour in two zones",
+			schedule: &Schedule{Months: map[int][]*Zone{
+				0: {
+					{Name: "day", Ranges: [][2]int{{0, 24}}},
+					{Name: "night", Ranges: [][2]int{{23, 24}}},
+				},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "months without zones",
+			schedule: &Schedule{Months: map[int][]*Zone{
+				1: {{Name: "day", Ranges: [][2]int{{0, 24}}}},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.schedule.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchedule_zone(t *testing.T) {
+	s := ThreeZoneSchedule()
+	s.Months[2] = []*Zone{
+		{Name: "day", Ranges: [][2]int{{8, 22}}},
+		{Name: "night", Ranges: [][2]int{{22, 24}, {0, 8}}},
+	}
+
+	tests := []struct {
+		name  string
+		shift int
+		t     time.Time
+		want  string
+	}{
+		{name: "january peak", t: time.Date(2020, 1, 10, 7, 0, 0, 0, time.UTC), want: "peak"},
+		{name: "january semi-peak", t: time.Date(2020, 1, 10, 22, 0, 0, 0, time.UTC), want: "semi-peak"},
+		{name: "february own zones", t: time.Date(2020, 2, 10, 7, 0, 0, 0, time.UTC), want: "night"},
+		{name: "shifted", shift: 1, t: time.Date(2020, 2, 10, 7, 0, 0, 0, time.UTC), want: "day"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.Shift = tt.shift
+			if got := s.zone(tt.t); got != tt.want {
+				t.Errorf("zone() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSchedule(t *testing.T) {
+	got, err := LoadSchedule("testdata/zones.json")
+	if err != nil {
+		t.Fatalf("LoadSchedule() error = %v", err)
+	}
+
+	want := &Schedule{
+		Name:  "moscow",
+		Shift: 0,
+		Months: map[int][]*Zone{
+			0: {
+				{Name: "day", Ranges: [][2]int{{7, 23}}},
+				{Name: "night", Ranges: [][2]int{{23, 24}, {0, 7}}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadSchedule() = %v, want %v", got, want)
+	}
+
+	if got, err := LoadSchedule(""); got != nil || err != nil {
+		t.Errorf("LoadSchedule() of empty name = %v, %v, want nil", got, err)
+	}
+}
+
+func TestApp_Run_zones(t *testing.T) {
+	a, err := New("testdata/23456789_feb.html", "98765432", "OOO STAR", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Schedule = DayNightSchedule()
+
+	err = a.Run()
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	defer os.RemoveAll("80020-02-2020")
+
+	if len(a.Zones) != 2 || a.Zones[0].Name != "day" || a.Zones[1].Name != "night" {
+		t.Fatalf("Run() zones = %v, want day and night", a.Zones)
+	}
+
+	sum := a.Zones[0].Total + a.Zones[1].Total
+	if sum-a.Total > 1e-9 || a.Total-sum > 1e-9 {
+		t.Errorf("Run() zones sum = %v, want %v", sum, a.Total)
+	}
+
+	if _, err := os.Stat(path.Join("80020-02-2020", "summary.txt")); err != nil {
+		t.Errorf("Run() summary file: %v", err)
+	}
+}